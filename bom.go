@@ -13,3 +13,15 @@ func hasUTF8BOM(content []byte) bool {
 		content[1] == UTF8BOM[1] &&
 		content[2] == UTF8BOM[2]
 }
+
+// writeUTF8BOM returns true when a BOM should be written in front of a file
+func (b ConfigBOM) writeUTF8BOM(hadUTF8BOM bool) bool {
+	switch b {
+	case ConfigAddBOM:
+		return true
+	case ConfigKeepBOM:
+		return hadUTF8BOM
+	default:
+		return false
+	}
+}
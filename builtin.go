@@ -0,0 +1,93 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+)
+
+const (
+	// builtinPrefix selects a built-in template instead of a template file: "builtin:apache-2.0"
+	builtinPrefix    = "builtin:"
+	builtinExtension = ".txt"
+)
+
+var (
+	//go:embed templates/*.txt
+	builtinTemplateFiles embed.FS
+
+	// builtinTemplates is the description of each built-in template
+	builtinTemplates = map[string]string{
+		"apache-2.0":       "Apache License 2.0",
+		"bsd-3-clause":     "BSD 3-Clause \"New\" or \"Revised\" License",
+		"gpl-3.0-or-later": "GNU General Public License v3.0 or later",
+		"mit":              "MIT License",
+		"mpl-2.0":          "Mozilla Public License 2.0",
+		"proprietary":      "All rights reserved, proprietary and confidential",
+	}
+)
+
+// isBuiltinTemplate returns true when the copyright file name designates a built-in template
+func isBuiltinTemplate(filename string) bool {
+	return strings.HasPrefix(filename, builtinPrefix)
+}
+
+// getBuiltinTemplate returns the text of a built-in template from its name (without the prefix)
+func getBuiltinTemplate(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, found := builtinTemplates[name]; !found {
+		return "", fmt.Errorf("unknown built-in template '%s' (available: %s)", name, strings.Join(builtinTemplateNames(), ", "))
+	}
+	content, err := builtinTemplateFiles.ReadFile("templates/" + name + builtinExtension)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// builtinTemplateNames returns the names of the built-in templates, sorted
+func builtinTemplateNames() []string {
+	names := make([]string, 0, len(builtinTemplates))
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadCopyrightTemplate parses a template file, or a built-in template when the name starts with "builtin:"
+func loadCopyrightTemplate(filename string) (*CopyrightTemplate, error) {
+	if !isBuiltinTemplate(filename) {
+		return ParseCopyrightTemplateFromFile(filename)
+	}
+	text, err := getBuiltinTemplate(strings.TrimPrefix(filename, builtinPrefix))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCopyrightTemplateFromString(text)
+}
+
+// runTemplates runs the templates command: "templates list" or "templates show <name>"
+func runTemplates(args []string, output io.Writer) int {
+	if len(args) == 0 || args[0] == "list" {
+		for _, name := range builtinTemplateNames() {
+			fmt.Fprintf(output, "  %-20s %s\n", builtinPrefix+name, builtinTemplates[name])
+		}
+		return 0
+	}
+	if args[0] != "show" || len(args) != 2 {
+		clog.Errorf("usage: copyright-notice %s list | show <name>", commandTemplates)
+		return exitCodeFailure
+	}
+	text, err := getBuiltinTemplate(strings.TrimPrefix(args[1], builtinPrefix))
+	if err != nil {
+		clog.Error(err)
+		return exitCodeFailure
+	}
+	fmt.Fprint(output, text)
+	return 0
+}
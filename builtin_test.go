@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinTemplateFiles(t *testing.T) {
+	files, err := fs.Glob(builtinTemplateFiles, "templates/*"+builtinExtension)
+	require.NoError(t, err)
+	names := make([]string, len(files))
+	for index, file := range files {
+		names[index] = strings.TrimSuffix(filepath.Base(file), builtinExtension)
+	}
+	assert.ElementsMatch(t, builtinTemplateNames(), names)
+}
+
+func TestBuiltinTemplatesDetectTheirOwnNotice(t *testing.T) {
+	data := CopyrightData{FirstYear: 2015, Year: 2020, Vars: map[string]string{"holder": "Test Corp."}}
+	for _, name := range builtinTemplateNames() {
+		for _, style := range []string{"c-block", "hash", "xml"} {
+			tmpl, err := loadCopyrightTemplate(builtinPrefix + name)
+			require.NoError(t, err)
+			comment := commentStyles[style]
+			tmpl = tmpl.WithVariables("test", data.Vars).WithCommentStyle(&comment)
+
+			notice, err := tmpl.GetCopyrightNotice(&data)
+			require.NoError(t, err)
+			assert.Contains(t, string(notice), "2015-2020 Test Corp.")
+
+			pattern, err := tmpl.GetRegexp()
+			require.NoError(t, err)
+			assert.True(t, pattern.Match(append(notice, []byte("\n"+testSource)...)), "%s in %s", name, style)
+		}
+	}
+}
+
+func TestUnknownBuiltinTemplate(t *testing.T) {
+	_, err := loadCopyrightTemplate("builtin:unknown")
+	assert.Error(t, err)
+}
+
+func TestBuiltinTemplateNeedsHolder(t *testing.T) {
+	tmpl, err := loadCopyrightTemplate("builtin:MIT")
+	require.NoError(t, err)
+	_, err = tmpl.GetCopyrightNotice(&CopyrightData{Year: 2020, Vars: map[string]string{}})
+	assert.Error(t, err)
+
+	_, err = loadNotice("test", ConfigProfile{Copyright: "builtin:mit"})
+	assert.Error(t, err)
+	_, err = loadNotice("test", ConfigProfile{Copyright: "builtin:mit", Variables: map[string]string{"holder": "TestCorp"}})
+	assert.NoError(t, err)
+}
+
+func TestRunTemplates(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	assert.Equal(t, 0, runTemplates([]string{"list"}, buffer))
+	for _, name := range builtinTemplateNames() {
+		assert.Contains(t, buffer.String(), builtinPrefix+name)
+	}
+
+	buffer.Reset()
+	assert.Equal(t, 0, runTemplates([]string{"show", "builtin:apache-2.0"}, buffer))
+	assert.Contains(t, buffer.String(), "Licensed under the Apache License, Version 2.0")
+
+	buffer.Reset()
+	assert.Equal(t, 0, runTemplates([]string{"show", "MIT"}, buffer))
+	assert.Contains(t, buffer.String(), "SPDX-License-Identifier: MIT")
+
+	assert.Equal(t, exitCodeFailure, runTemplates([]string{"show"}, buffer))
+	assert.Equal(t, exitCodeFailure, runTemplates([]string{"show", "unknown"}, buffer))
+	assert.Equal(t, exitCodeFailure, runTemplates([]string{"remove"}, buffer))
+}
+
+func TestApplyBuiltinTemplate(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"main.go":   testSource,
+		"script.py": "print('hello')\n",
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: [go, py]\n    year: update\n    copyright: builtin:mit\n    variables:\n      holder: TestCorp\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", configFile)
+	assert.Equal(t, 0, exitCode)
+	content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "/*\n * Copyright (c) "+currentYear+" TestCorp\n * SPDX-License-Identifier: MIT\n *\n"))
+	content, err = os.ReadFile(filepath.Join(dir, "script.py"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "# Copyright (c) "+currentYear+" TestCorp\n# SPDX-License-Identifier: MIT\n#\n"))
+
+	// the notices are detected
+	exitCode = runCommand(t, "--config", configFile, commandCheck)
+	assert.Equal(t, 0, exitCode)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/creativeprojects/clog"
+)
+
+// Exit codes of the check command. They are combined when a check finds more than one kind of problem
+const (
+	exitCodeFailure        = 1
+	exitCodeMissingHeader  = 2
+	exitCodeOutdatedYear   = 4
+	exitCodeErrors         = 8
+	exitCodeOutdatedHeader = 16
+	exitCodeSPDX           = 32
+)
+
+// checkExitCodes is the exit code for each status considered a failure by the check command
+var checkExitCodes = map[fileStatus]int{
+	fileStatusNoCopyright:               exitCodeMissingHeader,
+	fileStatusCopyrightYearNeedsUpdated: exitCodeOutdatedYear,
+	fileStatusHeaderReplaced:            exitCodeOutdatedHeader,
+	fileStatusCannotFindCopyrightYear:   exitCodeErrors,
+	fileStatusCannotOpen:                exitCodeErrors,
+	fileStatusMissingSPDX:               exitCodeSPDX,
+	fileStatusUnknownSPDX:               exitCodeSPDX,
+	fileStatusSPDXMismatch:              exitCodeSPDX,
+	fileStatusError:                     exitCodeErrors,
+}
+
+// checkExitCode returns the exit code of the check command for these results
+func checkExitCode(results *Results) int {
+	exitCode := 0
+	for status, code := range checkExitCodes {
+		if results.Len(status) > 0 {
+			exitCode |= code
+		}
+	}
+	return exitCode
+}
+
+// displayCheckResults displays each file failing the check, with its status
+func displayCheckResults(results *Results) {
+	for _, status := range []fileStatus{
+		fileStatusNoCopyright,
+		fileStatusCopyrightYearNeedsUpdated,
+		fileStatusHeaderReplaced,
+		fileStatusCannotFindCopyrightYear,
+		fileStatusCannotOpen,
+		fileStatusMissingSPDX,
+		fileStatusUnknownSPDX,
+		fileStatusSPDXMismatch,
+		fileStatusError,
+	} {
+		for _, result := range results.Get(status) {
+			if result.err != nil {
+				clog.Errorf("%s %s: %s: %s", status.Symbol(), result.fileName, status, result.err)
+				continue
+			}
+			clog.Warningf("%s %s: %s", status.Symbol(), result.fileName, status)
+		}
+	}
+}
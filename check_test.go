@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeprojects/clog"
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckExitCode(t *testing.T) {
+	testData := []struct {
+		status   []fileStatus
+		exitCode int
+	}{
+		{[]fileStatus{}, 0},
+		{[]fileStatus{fileStatusWithCopyright, fileStatusOtherCopyright, fileStatusAutoGenerated, fileStatusTooBig}, 0},
+		{[]fileStatus{fileStatusNoCopyright}, exitCodeMissingHeader},
+		{[]fileStatus{fileStatusCopyrightYearNeedsUpdated, fileStatusWithCopyright}, exitCodeOutdatedYear},
+		{[]fileStatus{fileStatusCannotOpen}, exitCodeErrors},
+		{[]fileStatus{fileStatusError, fileStatusCannotFindCopyrightYear}, exitCodeErrors},
+		{[]fileStatus{fileStatusNoCopyright, fileStatusCopyrightYearNeedsUpdated, fileStatusError}, exitCodeMissingHeader | exitCodeOutdatedYear | exitCodeErrors},
+		{[]fileStatus{fileStatusHeaderReplaced, fileStatusWithCopyright}, exitCodeOutdatedHeader},
+	}
+	for _, testItem := range testData {
+		results := NewResults()
+		for _, status := range testItem.status {
+			results.Add("file", status, nil)
+		}
+		assert.Equal(t, testItem.exitCode, checkExitCode(results), testItem.status)
+	}
+}
+
+// runCommand runs the tool with these command line arguments
+func runCommand(t *testing.T, args ...string) int {
+	t.Helper()
+	defer func(args []string, saved Flags) {
+		os.Args = args
+		*flags = saved
+		flag.VisitAll(func(f *flag.Flag) { f.Changed = false })
+		clog.SetDefaultLogger(clog.NewLogger(clog.NewDiscardHandler()))
+	}(os.Args, *flags)
+
+	os.Args = append([]string{"copyright-notice", "--output", filepath.Join(t.TempDir(), "output.log")}, args...)
+	return run()
+}
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	sources := map[string]string{
+		"with.go":     "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource,
+		"without.go":  testSource,
+		"untouched.c": testSource,
+	}
+	for name, content := range sources {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    year: update\n    copyright: " + copyright + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", configFile, commandCheck)
+	assert.Equal(t, exitCodeMissingHeader|exitCodeOutdatedYear, exitCode)
+
+	// nothing has changed
+	for name, content := range sources {
+		result, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(result))
+	}
+
+	exitCode = runCommand(t, "--config", filepath.Join(dir, "not-found.yaml"), commandCheck)
+	assert.Equal(t, exitCodeFailure, exitCode)
+
+	exitCode = runCommand(t, "--config", configFile, "unknown-command")
+	assert.Equal(t, exitCodeFailure, exitCode)
+
+	// a profile which cannot be checked is a failure
+	exitCode = runCommand(t, "--config", configFile, "--copyright", filepath.Join(dir, "not-found.txt"), commandCheck)
+	assert.Equal(t, exitCodeFailure, exitCode)
+
+	exitCode = runCommand(t, "--config", configFile, "--copyright", "builtin:mit", commandCheck)
+	assert.Equal(t, exitCodeFailure, exitCode)
+
+	incomplete := filepath.Join(dir, "incomplete.yaml")
+	require.NoError(t, os.WriteFile(incomplete, []byte("profiles:\n  test:\n    source: "+dir+"\n    copyright: "+copyright+"\n"), 0600))
+	exitCode = runCommand(t, "--config", incomplete, commandCheck)
+	assert.Equal(t, exitCodeFailure, exitCode)
+}
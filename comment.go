@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	commentStyleNone = "none"
+	commentStyleAuto = "auto"
+)
+
+// CommentStyle describes how to wrap a plain text notice into a comment
+type CommentStyle struct {
+	Start  string // line opening a block comment (empty for line comments)
+	Prefix string // prefix of each line of text
+	End    string // line closing a block comment (empty for line comments)
+}
+
+var (
+	// commentStyles is the registry of known comment styles
+	commentStyles = map[string]CommentStyle{
+		"c-block":   {Start: "/*", Prefix: " * ", End: " */"},
+		"c-line":    {Prefix: "// "},
+		"hash":      {Prefix: "# "},
+		"dash":      {Prefix: "-- "},
+		"semicolon": {Prefix: "; "},
+		"xml":       {Start: "<!--", Prefix: "  ", End: "-->"},
+		"haskell":   {Start: "{-", Prefix: "  ", End: "-}"},
+		"ml":        {Start: "(*", Prefix: "  ", End: "*)"},
+		"rem":       {Prefix: "REM "},
+	}
+
+	// extensionCommentStyles is the comment style by default for each file extension
+	extensionCommentStyles = map[string]string{
+		".c":     "c-block",
+		".h":     "c-block",
+		".cc":    "c-block",
+		".cpp":   "c-block",
+		".hpp":   "c-block",
+		".cs":    "c-block",
+		".css":   "c-block",
+		".scss":  "c-block",
+		".less":  "c-block",
+		".go":    "c-block",
+		".java":  "c-block",
+		".kt":    "c-block",
+		".scala": "c-block",
+		".js":    "c-block",
+		".jsx":   "c-block",
+		".ts":    "c-block",
+		".tsx":   "c-block",
+		".php":   "c-block",
+		".rs":    "c-block",
+		".swift": "c-block",
+		".dart":  "c-block",
+		".proto": "c-line",
+		".py":    "hash",
+		".rb":    "hash",
+		".pl":    "hash",
+		".sh":    "hash",
+		".bash":  "hash",
+		".zsh":   "hash",
+		".ps1":   "hash",
+		".r":     "hash",
+		".yml":   "hash",
+		".yaml":  "hash",
+		".toml":  "hash",
+		".tf":    "hash",
+		".mk":    "hash",
+		".sql":   "dash",
+		".lua":   "dash",
+		".ada":   "dash",
+		".el":    "semicolon",
+		".lisp":  "semicolon",
+		".clj":   "semicolon",
+		".ini":   "semicolon",
+		".asm":   "semicolon",
+		".html":  "xml",
+		".htm":   "xml",
+		".xml":   "xml",
+		".xaml":  "xml",
+		".svg":   "xml",
+		".vue":   "xml",
+		".md":    "xml",
+		".hs":    "haskell",
+		".elm":   "haskell",
+		".ml":    "ml",
+		".mli":   "ml",
+		".fs":    "ml",
+		".pas":   "ml",
+		".bat":   "rem",
+		".cmd":   "rem",
+	}
+)
+
+// getCommentStyle returns the comment style to use for the file extension:
+// the style can be "none", "auto" (detected from the extension) or the name of a style in the registry.
+// It returns nil when the notice should not be wrapped into a comment
+func getCommentStyle(style, extension string) (*CommentStyle, bool) {
+	if style == "" || style == commentStyleNone {
+		return nil, true
+	}
+	if style == commentStyleAuto {
+		style = extensionCommentStyles[strings.ToLower(extension)]
+		if style == "" {
+			return nil, false
+		}
+	}
+	comment, found := commentStyles[style]
+	if !found {
+		return nil, false
+	}
+	return &comment, true
+}
+
+// Wrap the text into a comment followed by a blank line,
+// so the notice is not attached to the code (like the documentation of a Go package)
+func (c CommentStyle) Wrap(text string) string {
+	builder := &strings.Builder{}
+	if c.Start != "" {
+		builder.WriteString(c.Start + "\n")
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			builder.WriteString(strings.TrimRight(c.Prefix, " \t") + "\n")
+			continue
+		}
+		builder.WriteString(c.Prefix + line + "\n")
+	}
+	if c.End != "" {
+		builder.WriteString(c.End + "\n")
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// isCommentText returns true when the text already starts with the syntax of a known comment style
+func isCommentText(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, style := range commentStyles {
+			marker := style.Start
+			if marker == "" {
+				marker = strings.TrimSpace(style.Prefix)
+			}
+			if strings.HasPrefix(line, marker) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentStyleWrap(t *testing.T) {
+	text := "Copyright 2020 TestCorp\n\nAll Rights Reserved\n"
+	testData := map[string]string{
+		"c-block":   "/*\n * Copyright 2020 TestCorp\n *\n * All Rights Reserved\n */\n\n",
+		"c-line":    "// Copyright 2020 TestCorp\n//\n// All Rights Reserved\n\n",
+		"hash":      "# Copyright 2020 TestCorp\n#\n# All Rights Reserved\n\n",
+		"dash":      "-- Copyright 2020 TestCorp\n--\n-- All Rights Reserved\n\n",
+		"semicolon": "; Copyright 2020 TestCorp\n;\n; All Rights Reserved\n\n",
+		"xml":       "<!--\n  Copyright 2020 TestCorp\n\n  All Rights Reserved\n-->\n\n",
+		"haskell":   "{-\n  Copyright 2020 TestCorp\n\n  All Rights Reserved\n-}\n\n",
+		"ml":        "(*\n  Copyright 2020 TestCorp\n\n  All Rights Reserved\n*)\n\n",
+		"rem":       "REM Copyright 2020 TestCorp\nREM\nREM All Rights Reserved\n\n",
+	}
+	require.Len(t, commentStyles, len(testData))
+	for name, expected := range testData {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, commentStyles[name].Wrap(text))
+		})
+	}
+}
+
+func TestGetCommentStyle(t *testing.T) {
+	testData := []struct {
+		style     string
+		extension string
+		expected  *CommentStyle
+		found     bool
+	}{
+		{"", ".go", nil, true},
+		{commentStyleNone, ".go", nil, true},
+		{commentStyleAuto, ".go", &CommentStyle{Start: "/*", Prefix: " * ", End: " */"}, true},
+		{commentStyleAuto, ".PY", &CommentStyle{Prefix: "# "}, true},
+		{commentStyleAuto, ".unknown", nil, false},
+		{"hash", ".go", &CommentStyle{Prefix: "# "}, true},
+		{"unknown", ".go", nil, false},
+	}
+	for _, testItem := range testData {
+		style, found := getCommentStyle(testItem.style, testItem.extension)
+		assert.Equal(t, testItem.expected, style, testItem)
+		assert.Equal(t, testItem.found, found, testItem)
+	}
+}
+
+func TestExtensionCommentStylesExist(t *testing.T) {
+	for extension, style := range extensionCommentStyles {
+		_, found := commentStyles[style]
+		assert.True(t, found, "style %q of extension %q not found", style, extension)
+	}
+}
+
+func TestIsCommentText(t *testing.T) {
+	testData := map[string]bool{
+		"/*\n * Copyright\n */\n":   true,
+		"\n// Copyright\n":          true,
+		"# Copyright\n":             true,
+		"<!--\n  Copyright\n-->\n":  true,
+		"Copyright\n// not first\n": false,
+		"":                          false,
+	}
+	for text, expected := range testData {
+		assert.Equal(t, expected, isCommentText(text), text)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultCommitMessage is used when the profile doesn't define a commit-message
+const defaultCommitMessage = "Update copyright notices"
+
+// CommitMessageData is the data available to the commit-message template
+type CommitMessageData struct {
+	Profile  string
+	Files    int // number of files in the commit
+	Added    int // number of files where a copyright notice was added
+	Updated  int // number of files where the year was updated
+	Replaced int // number of files where an outdated notice was replaced
+	Removed  int // number of files where the notice was removed
+	Year     int
+}
+
+// Commit is a commit created in a git repository
+type Commit struct {
+	Repository string
+	Hash       string
+	Files      int
+}
+
+// commitChanges commits the files modified by the profile. It creates one commit in each git repository containing modified files
+func commitChanges(profileName string, profile ConfigProfile, results *Results) ([]Commit, error) {
+	added := results.Get(fileStatusNoCopyright)
+	updated := results.Get(fileStatusCopyrightYearNeedsUpdated)
+	replaced := results.Get(fileStatusHeaderReplaced)
+	removed := results.Get(fileStatusHeaderRemoved)
+	if len(added)+len(updated)+len(replaced)+len(removed) == 0 {
+		return nil, nil
+	}
+	data := CommitMessageData{
+		Profile:  profileName,
+		Added:    len(added),
+		Updated:  len(updated),
+		Replaced: len(replaced),
+		Removed:  len(removed),
+		Year:     time.Now().Year(),
+	}
+
+	// group the files by repository
+	repositories := make(map[string][]string)
+	roots := make(gitRoots)
+	modified := append(append(append(added, updated...), replaced...), removed...)
+	for _, result := range modified {
+		root, err := roots.get(filepath.Dir(result.fileName))
+		if err != nil {
+			return nil, fmt.Errorf("cannot commit file '%s': %w", result.fileName, err)
+		}
+		repositories[root] = append(repositories[root], result.fileName)
+	}
+	sortedRoots := make([]string, 0, len(repositories))
+	for root := range repositories {
+		sortedRoots = append(sortedRoots, root)
+	}
+	sort.Strings(sortedRoots)
+
+	commits := make([]Commit, 0, len(sortedRoots))
+	for _, root := range sortedRoots {
+		data.Files = len(repositories[root])
+		message, err := getCommitMessage(profile.CommitMessage, data)
+		if err != nil {
+			return commits, err
+		}
+		hash, err := gitCommit(root, repositories[root], message, profile.CommitAuthor)
+		if err != nil {
+			return commits, fmt.Errorf("cannot commit in repository '%s': %w", root, err)
+		}
+		commits = append(commits, Commit{Repository: root, Hash: hash, Files: data.Files})
+	}
+	return commits, nil
+}
+
+// checkStagedChanges returns an error when a git repository containing some of the files already has staged changes:
+// they would end up in the commit of the copyright notices. It runs before any file is modified
+func checkStagedChanges(fileQueue *list.List) error {
+	roots := make(gitRoots)
+	checked := make(map[string]bool)
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		fileName := e.Value.(FileEntry).Name
+		root, err := roots.get(filepath.Dir(fileName))
+		if err != nil {
+			return fmt.Errorf("cannot commit file '%s': %w", fileName, err)
+		}
+		if checked[root] {
+			continue
+		}
+		checked[root] = true
+		staged, err := gitStagedChanges(root)
+		if err != nil {
+			return fmt.Errorf("cannot commit in repository '%s': %w", root, err)
+		}
+		if len(staged) > 0 {
+			return fmt.Errorf("cannot commit in repository '%s': some changes are already staged: %s", root, strings.Join(staged, ", "))
+		}
+	}
+	return nil
+}
+
+// gitRoots keeps the root of the git repository of each directory. It is not safe for concurrent use
+type gitRoots map[string]string
+
+// get returns the root of the git repository containing the directory
+func (r gitRoots) get(directory string) (string, error) {
+	if root, found := r[directory]; found {
+		return root, nil
+	}
+	root, err := gitRoot(directory)
+	if err != nil {
+		return "", err
+	}
+	r[directory] = root
+	return root, nil
+}
+
+// gitStagedChanges returns the files with changes in the index of the repository, relative to its root
+func gitStagedChanges(root string) ([]string, error) {
+	output, err := git(root, "diff", "--cached", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(output), nil
+}
+
+// getCommitMessage generates the commit message from the template
+func getCommitMessage(messageTemplate string, data CommitMessageData) (string, error) {
+	if messageTemplate == "" {
+		return defaultCommitMessage, nil
+	}
+	tmpl, err := template.New("commit-message").Parse(messageTemplate)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse commit message template: %w", err)
+	}
+	buffer := &bytes.Buffer{}
+	err = tmpl.Execute(buffer, data)
+	if err != nil {
+		return "", fmt.Errorf("cannot generate commit message: %w", err)
+	}
+	message := strings.TrimSpace(buffer.String())
+	if message == "" {
+		return "", errors.New("the commit message is empty")
+	}
+	return message, nil
+}
+
+// gitCommit stages the files and commits them. It refuses to commit if some changes were already staged,
+// even in the same files. It returns the hash of the new commit
+func gitCommit(root string, files []string, message, author string) (string, error) {
+	relativeFiles := make([]string, len(files))
+	for index, file := range files {
+		relative, err := gitRelativePath(root, file)
+		if err != nil {
+			return "", err
+		}
+		relativeFiles[index] = relative
+	}
+
+	staged, err := gitStagedChanges(root)
+	if err != nil {
+		return "", err
+	}
+	if len(staged) > 0 {
+		return "", fmt.Errorf("some changes are already staged: %s", strings.Join(staged, ", "))
+	}
+
+	_, err = git(root, append([]string{"add", "--"}, relativeFiles...)...)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"commit", "--quiet", "--message", message}
+	if author != "" {
+		args = append(args, "--author", author)
+	}
+	_, err = git(root, args...)
+	if err != nil {
+		return "", err
+	}
+	output, err := git(root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
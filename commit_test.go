@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitMessage(t *testing.T) {
+	data := CommitMessageData{Profile: "self", Files: 3, Added: 2, Updated: 1, Year: 2023}
+	testData := []struct {
+		template string
+		message  string
+	}{
+		{"", defaultCommitMessage},
+		{"Copyright notices", "Copyright notices"},
+		{"[{{ .Profile }}] {{ .Added }} added, {{ .Updated }} updated for {{ .Year }}\n", "[self] 2 added, 1 updated for 2023"},
+		{"{{ .Files }} files", "3 files"},
+	}
+	for _, testItem := range testData {
+		message, err := getCommitMessage(testItem.template, data)
+		require.NoError(t, err)
+		assert.Equal(t, testItem.message, message)
+	}
+
+	_, err := getCommitMessage("{{ .Unknown }}", data)
+	assert.Error(t, err)
+	_, err = getCommitMessage("{{ .Profile", data)
+	assert.Error(t, err)
+	_, err = getCommitMessage("{{ \"\" }}", data)
+	assert.Error(t, err)
+}
+
+// initGitRepository creates an empty git repository with a default identity
+func initGitRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	dir := t.TempDir()
+	_, err := git(dir, "init", "--quiet")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestCommitChanges(t *testing.T) {
+	dir := initGitRepository(t)
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	sources := map[string]string{
+		"with.go":            "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource,
+		"without.go":         testSource,
+		"pkg/without.go":     testSource,
+		"untouched.c":        testSource,
+		"up-to-date/file.go": "/*\n * Copyright (C) 2015-2999 TestCorp.\n */\n" + testSource,
+	}
+	writeTestTree(t, dir, sources)
+	_, err := git(dir, "add", ".")
+	require.NoError(t, err)
+	_, err = git(dir, "commit", "--quiet", "--message", "initial commit")
+	require.NoError(t, err)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    year: update\n    copyright: " + copyright +
+		"\n    commit-changes: true\n    commit-message: '{{ .Added }} added, {{ .Updated }} updated'\n    commit-author: 'Copyright Bot <bot@example.com>'\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	// an unrelated staged change prevents the commit
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "untouched.c"), []byte("staged"), 0600))
+	_, err = git(dir, "add", "untouched.c")
+	require.NoError(t, err)
+
+	exitCode := runCommand(t, "--config", configFile)
+	assert.Equal(t, exitCodeFailure, exitCode)
+	output, err := git(dir, "log", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, "initial commit\n", output)
+	// and nothing was modified
+	output, err = git(dir, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, "M  untouched.c\n?? config.yaml\n", output)
+
+	_, err = git(dir, "reset", "--quiet", "--hard")
+	require.NoError(t, err)
+
+	// a staged change in a file which needs a notice would be part of the commit too
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "without.go"), []byte(testSource+"\nfunc staged() {}\n"), 0600))
+	_, err = git(dir, "add", "without.go")
+	require.NoError(t, err)
+
+	exitCode = runCommand(t, "--config", configFile)
+	assert.Equal(t, exitCodeFailure, exitCode)
+	output, err = git(dir, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, "M  without.go\n?? config.yaml\n", output)
+
+	_, err = git(dir, "reset", "--quiet", "--hard")
+	require.NoError(t, err)
+
+	exitCode = runCommand(t, "--config", configFile)
+	assert.Equal(t, 0, exitCode)
+	output, err = git(dir, "log", "-1", "--format=%s|%an <%ae>")
+	require.NoError(t, err)
+	assert.Equal(t, "2 added, 1 updated|Copyright Bot <bot@example.com>\n", output)
+
+	output, err = git(dir, "show", "--name-only", "--format=", "HEAD")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"pkg/without.go", "with.go", "without.go"}, strings.Fields(output))
+
+	// the working tree is clean
+	output, err = git(dir, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, "?? config.yaml\n", output)
+
+	// nothing left to commit
+	exitCode = runCommand(t, "--config", configFile)
+	assert.Equal(t, 0, exitCode)
+	output, err = git(dir, "rev-list", "--count", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", output)
+}
+
+func TestNoCommitInDryRun(t *testing.T) {
+	dir := initGitRepository(t)
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	writeTestTree(t, dir, map[string]string{"without.go": testSource})
+	_, err := git(dir, "add", ".")
+	require.NoError(t, err)
+	_, err = git(dir, "commit", "--quiet", "--message", "initial commit")
+	require.NoError(t, err)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    copyright: " + copyright + "\n    commit-changes: true\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", configFile, "--dry-run")
+	assert.Equal(t, 0, exitCode)
+	output, err := git(dir, "rev-list", "--count", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", output)
+}
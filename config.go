@@ -1,20 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 
+	"github.com/creativeprojects/clog"
 	"gopkg.in/yaml.v2"
 )
 
 // StringSlice is used to allow a YAML field to be represented by a single string or an array of strings
 //
-//   ---
-//   works: "single"
-//   also-works:
-//     - "one"
-//     - "two"
+//	---
+//	works: "single"
+//	also-works:
+//	  - "one"
+//	  - "two"
 type StringSlice []string
 
 // UnmarshalYAML a string or a list of strings into a slice
@@ -34,35 +42,50 @@ func (a *StringSlice) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// replaceMarker as the first item of a list replaces the inherited list instead of appending to it
+const replaceMarker = "<replace>"
+
 type Config struct {
 	MaxFileSize       int64                    `yaml:"max-file-size"`
 	DefaultBufferSize int                      `yaml:"default-buffer-size"`
+	Workers           int                      `yaml:"workers"`
+	Include           *StringSlice             `yaml:"include"`  // configuration files to load the profiles from
+	Defaults          ConfigProfile            `yaml:"defaults"` // inherited by the profiles not extending another one
 	Profiles          map[string]ConfigProfile `yaml:"profiles"`
+	order             []string                 // names of the profiles in the order of declaration
 }
 
 type ConfigProfile struct {
-	Source               *StringSlice `yaml:"source"`     // Mandatory
-	Extensions           *StringSlice `yaml:"extensions"` // Mandatory
-	Copyright            string       `yaml:"copyright"`  // Mandatory
-	BOM                  string       `yaml:"utf8-bom"`
-	Year                 *ConfigYear  `yaml:"year"`
-	Excludes             *StringSlice `yaml:"excludes"`
-	ExcludeFrom          string       `yaml:"exclude-from"`
-	ExcludeFromGitIgnore string       `yaml:"exclude-gitignore"`
-	DetectOwn            string       `yaml:"detect-own"`
-	DetectOthers         string       `yaml:"detect-others"`
-	CommitChanges        string       `yaml:"commit-changes"`
-	CommitMessage        string       `yaml:"commit-message"`
-	CommitAuthor         string       `yaml:"commit-author"`
-	Output               string       `yaml:"output"`
+	Extends              string                  `yaml:"extends"`    // name of the profile to inherit from
+	Source               *StringSlice            `yaml:"source"`     // Mandatory
+	Extensions           *StringSlice            `yaml:"extensions"` // Mandatory
+	Copyright            string                  `yaml:"copyright"`  // Mandatory
+	Replaces             *StringSlice            `yaml:"replaces"`
+	Variables            map[string]string       `yaml:"variables"`
+	SPDX                 string                  `yaml:"spdx"`
+	CommentStyle         string                  `yaml:"comment-style"`
+	Preambles            map[string]*StringSlice `yaml:"preambles"`
+	BOM                  *ConfigBOM              `yaml:"utf8-bom"`
+	Year                 *ConfigYear             `yaml:"year"`
+	YearRange            *ConfigYearRange        `yaml:"year-range"`
+	YearSource           *ConfigYearSource       `yaml:"year-source"`
+	Excludes             *StringSlice            `yaml:"excludes"`
+	ExcludeFrom          string                  `yaml:"exclude-from"`
+	ExcludeFromGitIgnore *bool                   `yaml:"exclude-gitignore"`
+	DetectOwn            string                  `yaml:"detect-own"`
+	DetectOthers         string                  `yaml:"detect-others"`
+	CommitChanges        *bool                   `yaml:"commit-changes"`
+	CommitMessage        string                  `yaml:"commit-message"`
+	CommitAuthor         string                  `yaml:"commit-author"`
+	Output               string                  `yaml:"output"`
 }
 
 type ConfigYear int
 
-// ConfigYear
+// ConfigYear: the year is left as it is by default, and only removed from the notice with "none"
 const (
-	ConfigNoYear ConfigYear = iota
-	ConfigLeaveYear
+	ConfigLeaveYear ConfigYear = iota
+	ConfigNoYear
 	ConfigUpdateYear
 )
 
@@ -75,12 +98,96 @@ func (y *ConfigYear) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 	value = strings.ToLower(value)
 	switch value {
-	case "leave":
-		*y = ConfigLeaveYear
+	case "none":
+		*y = ConfigNoYear
 	case "update":
 		*y = ConfigUpdateYear
 	default:
-		*y = ConfigNoYear
+		*y = ConfigLeaveYear
+	}
+	return nil
+}
+
+type ConfigYearRange int
+
+// ConfigYearRange
+const (
+	ConfigExtendYearRange ConfigYearRange = iota
+	ConfigReplaceYearRange
+)
+
+// UnmarshalYAML into a ConfigYearRange
+func (r *ConfigYearRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	value := ""
+	err := unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	value = strings.ToLower(value)
+	switch value {
+	case "replace":
+		*r = ConfigReplaceYearRange
+	default:
+		*r = ConfigExtendYearRange
+	}
+	return nil
+}
+
+type ConfigYearSource int
+
+// ConfigYearSource
+const (
+	ConfigYearFromClock ConfigYearSource = iota
+	ConfigYearFromGitFirstCommit
+	ConfigYearFromGitLastCommit
+	ConfigYearFromModTime
+)
+
+// UnmarshalYAML into a ConfigYearSource
+func (s *ConfigYearSource) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	value := ""
+	err := unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	value = strings.ToLower(value)
+	switch value {
+	case "git-first-commit":
+		*s = ConfigYearFromGitFirstCommit
+	case "git-last-commit":
+		*s = ConfigYearFromGitLastCommit
+	case "mtime":
+		*s = ConfigYearFromModTime
+	default:
+		*s = ConfigYearFromClock
+	}
+	return nil
+}
+
+type ConfigBOM int
+
+// ConfigBOM
+const (
+	ConfigForgetBOM ConfigBOM = iota
+	ConfigKeepBOM
+	ConfigAddBOM
+)
+
+// UnmarshalYAML into a ConfigBOM
+func (b *ConfigBOM) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	value := ""
+	err := unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	value = strings.ToLower(value)
+	switch value {
+	case "keep":
+		*b = ConfigKeepBOM
+	case "add":
+		*b = ConfigAddBOM
+	default:
+		*b = ConfigForgetBOM
 	}
 	return nil
 }
@@ -90,48 +197,324 @@ func NewConfig() Config {
 	return Config{
 		MaxFileSize:       maxFileSize,
 		DefaultBufferSize: defaultBufferSize,
+		Workers:           runtime.NumCPU(),
 	}
 }
 
-// LoadFileConfig loads YAML configuration from a file
+// LoadFileConfig loads YAML configuration from a file. The included files are relative to its directory
 func LoadFileConfig(filename string) (Config, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return NewConfig(), err
 	}
 	defer file.Close()
-	return LoadConfig(file)
+	return loadConfig(file, filepath.Dir(filename), []string{absolutePath(filename)})
 }
 
-// LoadConfig loads YAML configuration from a reader
+// LoadConfig loads YAML configuration from a reader. The included files are relative to the current directory
 func LoadConfig(reader io.Reader) (Config, error) {
-	decoder := yaml.NewDecoder(reader)
+	return loadConfig(reader, ".", nil)
+}
+
+// loadConfig loads the configuration with its included files, and resolves the profiles inheritance.
+// The stack is the list of the configuration files being loaded, to detect an include cycle
+func loadConfig(reader io.Reader, dir string, stack []string) (Config, error) {
 	config := NewConfig()
-	err := decoder.Decode(&config)
+	err := decodeConfig(reader, &config)
+	if err == nil {
+		err = includeProfiles(&config, dir, stack)
+	}
+	if err == nil {
+		err = resolveProfiles(&config)
+	}
 	cleanupConfig(&config)
 	return config, err
 }
 
-func cleanupConfig(config *Config) {
-	for _, profile := range config.Profiles {
-		// we'll prefix the files extension by a dot if it's not there yet
-		if profile.Extensions != nil {
-			for index, extension := range *profile.Extensions {
-				if extension[0] != '.' {
-					extension = "." + extension
-				}
-				(*profile.Extensions)[index] = extension
+// decodeConfig decodes the YAML configuration, and keeps the order of declaration of the profiles
+func decodeConfig(reader io.Reader, config *Config) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	err = yaml.NewDecoder(bytes.NewReader(content)).Decode(config)
+	if err != nil {
+		return err
+	}
+	order := struct {
+		Profiles yaml.MapSlice `yaml:"profiles"`
+	}{}
+	if yaml.Unmarshal(content, &order) == nil {
+		for _, item := range order.Profiles {
+			config.order = append(config.order, fmt.Sprint(item.Key))
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the profiles in the order of declaration.
+// The profiles with an unknown order (added without loading a file) come last, sorted by name
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	found := make(map[string]bool, len(c.Profiles))
+	for _, name := range c.order {
+		if _, exists := c.Profiles[name]; exists && !found[name] {
+			names = append(names, name)
+			found[name] = true
+		}
+	}
+	others := make([]string, 0)
+	for name := range c.Profiles {
+		if !found[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// includeProfiles adds the profiles of the included files. A profile of the configuration takes precedence
+// over an included profile with the same name, and the first included file takes precedence over the next ones
+func includeProfiles(config *Config, dir string, stack []string) error {
+	if config.Include == nil {
+		return nil
+	}
+	for _, filename := range *config.Include {
+		filename = os.ExpandEnv(filename)
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		absFilename := absolutePath(filename)
+		for _, loading := range stack {
+			if loading == absFilename {
+				return fmt.Errorf("include cycle: %s", strings.Join(append(stack, absFilename), " -> "))
 			}
 		}
-		// we expend the environment variables in paths
-		if profile.Source != nil {
-			for index, dir := range *profile.Source {
-				(*profile.Source)[index] = os.ExpandEnv(dir)
+		included, err := loadIncludedFile(filename, append(stack, absFilename))
+		if err != nil {
+			return fmt.Errorf("cannot include '%s': %w", filename, err)
+		}
+		if config.Profiles == nil {
+			config.Profiles = make(map[string]ConfigProfile, len(included.Profiles))
+		}
+		for _, name := range included.ProfileNames() {
+			if _, found := config.Profiles[name]; !found {
+				config.Profiles[name] = included.Profiles[name]
+				config.order = append(config.order, name)
 			}
 		}
-		// make sure year has a default value
-		if profile.Year == nil {
-			profile.Year = new(ConfigYear)
+	}
+	return nil
+}
+
+// loadIncludedFile loads the profiles of an included file with its own included files.
+// The profiles are not resolved yet: they can extend a profile from another file
+func loadIncludedFile(filename string, stack []string) (Config, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+	config := Config{}
+	err = decodeConfig(file, &config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return config, err
+	}
+	if !reflect.DeepEqual(config.Defaults, ConfigProfile{}) {
+		clog.Warningf("the defaults of the included file '%s' are ignored: only the defaults of the main configuration file apply", filename)
+	}
+	err = includeProfiles(&config, filepath.Dir(filename), stack)
+	return config, err
+}
+
+// resolveProfiles merges each profile with the profile it extends, or with the defaults
+func resolveProfiles(config *Config) error {
+	if config.Defaults.Extends != "" {
+		return errors.New("the defaults cannot extend a profile")
+	}
+	resolved := make(map[string]ConfigProfile, len(config.Profiles))
+	for name := range config.Profiles {
+		_, err := resolveProfile(config, name, resolved, nil)
+		if err != nil {
+			return err
+		}
+	}
+	config.Profiles = resolved
+	return nil
+}
+
+func resolveProfile(config *Config, name string, resolved map[string]ConfigProfile, stack []string) (ConfigProfile, error) {
+	if profile, found := resolved[name]; found {
+		return profile, nil
+	}
+	for _, extending := range stack {
+		if extending == name {
+			return ConfigProfile{}, fmt.Errorf("profile inheritance cycle: %s", strings.Join(append(stack, name), " -> "))
 		}
 	}
+	profile, found := config.Profiles[name]
+	if !found {
+		return ConfigProfile{}, fmt.Errorf("profile '%s' extends unknown profile '%s'", stack[len(stack)-1], name)
+	}
+	parent := config.Defaults
+	if profile.Extends != "" {
+		var err error
+		parent, err = resolveProfile(config, profile.Extends, resolved, append(stack, name))
+		if err != nil {
+			return ConfigProfile{}, err
+		}
+	}
+	profile = profile.inherit(parent)
+	resolved[name] = profile
+	return profile, nil
+}
+
+// inherit returns the profile with the fields it doesn't define taken from the parent.
+// The lists are appended to the parent lists, unless they start with the replace marker.
+// The maps are merged, and the other options are only inherited when the profile doesn't set them
+func (p ConfigProfile) inherit(parent ConfigProfile) ConfigProfile {
+	p.Source = inheritSlice(p.Source, parent.Source)
+	p.Extensions = inheritSlice(p.Extensions, parent.Extensions)
+	p.Replaces = inheritSlice(p.Replaces, parent.Replaces)
+	p.Excludes = inheritSlice(p.Excludes, parent.Excludes)
+	p.Variables = inheritMap(p.Variables, parent.Variables)
+	if parent.Preambles != nil {
+		preambles := make(map[string]*StringSlice, len(parent.Preambles)+len(p.Preambles))
+		for extension, rules := range parent.Preambles {
+			preambles[extension] = rules
+		}
+		for extension, rules := range p.Preambles {
+			preambles[extension] = inheritSlice(rules, parent.Preambles[extension])
+		}
+		p.Preambles = preambles
+	}
+	inheritString(&p.Copyright, parent.Copyright)
+	inheritString(&p.SPDX, parent.SPDX)
+	inheritString(&p.CommentStyle, parent.CommentStyle)
+	inheritString(&p.ExcludeFrom, parent.ExcludeFrom)
+	inheritString(&p.DetectOwn, parent.DetectOwn)
+	inheritString(&p.DetectOthers, parent.DetectOthers)
+	inheritString(&p.CommitMessage, parent.CommitMessage)
+	inheritString(&p.CommitAuthor, parent.CommitAuthor)
+	inheritString(&p.Output, parent.Output)
+	if p.BOM == nil {
+		p.BOM = parent.BOM
+	}
+	if p.Year == nil {
+		p.Year = parent.Year
+	}
+	if p.YearRange == nil {
+		p.YearRange = parent.YearRange
+	}
+	if p.YearSource == nil {
+		p.YearSource = parent.YearSource
+	}
+	if p.ExcludeFromGitIgnore == nil {
+		p.ExcludeFromGitIgnore = parent.ExcludeFromGitIgnore
+	}
+	if p.CommitChanges == nil {
+		p.CommitChanges = parent.CommitChanges
+	}
+	return p
+}
+
+// inheritSlice returns a new list with the parent items followed by the profile items,
+// or only the profile items when the list starts with the replace marker
+func inheritSlice(list, parent *StringSlice) *StringSlice {
+	if list != nil && len(*list) > 0 && (*list)[0] == replaceMarker {
+		replaced := append(StringSlice{}, (*list)[1:]...)
+		return &replaced
+	}
+	if parent == nil {
+		return list
+	}
+	merged := append(StringSlice{}, *parent...)
+	if list != nil {
+		merged = append(merged, *list...)
+	}
+	return &merged
+}
+
+func inheritMap(values, parent map[string]string) map[string]string {
+	if parent == nil {
+		return values
+	}
+	merged := make(map[string]string, len(parent)+len(values))
+	for key, value := range parent {
+		merged[key] = value
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	return merged
+}
+
+// isTrue returns true when a boolean option is set and enabled
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+func inheritString(value *string, parent string) {
+	if *value == "" {
+		*value = parent
+	}
+}
+
+func cleanupConfig(config *Config) {
+	for name, profile := range config.Profiles {
+		// profile is a copy of the map value
+		config.Profiles[name] = cleanupProfile(profile)
+	}
+}
+
+// cleanupProfile normalizes the extensions and the paths of the profile, and sets the default values
+func cleanupProfile(profile ConfigProfile) ConfigProfile {
+	// we'll prefix the files extension by a dot if it's not there yet
+	// (and an empty extension is ignored)
+	if profile.Extensions != nil {
+		extensions := make(StringSlice, 0, len(*profile.Extensions))
+		for _, extension := range *profile.Extensions {
+			if strings.Trim(extension, ". \t") == "" {
+				continue
+			}
+			if extension[0] != '.' {
+				extension = "." + extension
+			}
+			extensions = append(extensions, extension)
+		}
+		profile.Extensions = &extensions
+	}
+	// same for the extensions of the preamble rules
+	if profile.Preambles != nil {
+		preambles := make(map[string]*StringSlice, len(profile.Preambles))
+		for extension, rules := range profile.Preambles {
+			preambles[normalizePreambleExtension(extension)] = rules
+		}
+		profile.Preambles = preambles
+	}
+	// we expend the environment variables in paths
+	if profile.Source != nil {
+		for index, dir := range *profile.Source {
+			(*profile.Source)[index] = os.ExpandEnv(dir)
+		}
+	}
+	// the built-in templates are plain text
+	if isBuiltinTemplate(profile.Copyright) && profile.CommentStyle == "" {
+		profile.CommentStyle = commentStyleAuto
+	}
+	// make sure year has a default value
+	if profile.Year == nil {
+		profile.Year = new(ConfigYear)
+	}
+	if profile.YearRange == nil {
+		profile.YearRange = new(ConfigYearRange)
+	}
+	if profile.YearSource == nil {
+		profile.YearSource = new(ConfigYearSource)
+	}
+	// and the BOM too
+	if profile.BOM == nil {
+		profile.BOM = new(ConfigBOM)
+	}
+	return profile
 }
@@ -2,6 +2,7 @@
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,3 +54,255 @@ func TestCleanupFileExtensions(t *testing.T) {
 	assert.ElementsMatch(t, []string(*config.Profiles["first"].Extensions), []string{".js", ".ts"})
 	assert.ElementsMatch(t, []string(*config.Profiles["second"].Extensions), []string{".cs", ".yaml"})
 }
+
+func TestLoadYearPolicy(t *testing.T) {
+	source := `---
+profiles:
+  update:
+    year: update
+  leave:
+    year: Leave
+  no-year:
+    year: none
+  default:
+    source: .
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+	expected := map[string]ConfigYear{
+		"update":  ConfigUpdateYear,
+		"leave":   ConfigLeaveYear,
+		"no-year": ConfigNoYear,
+		"default": ConfigLeaveYear,
+	}
+	for name, year := range expected {
+		require.NotNil(t, config.Profiles[name].Year, name)
+		assert.Equal(t, year, *config.Profiles[name].Year, name)
+	}
+}
+
+func TestLoadBOMOption(t *testing.T) {
+	source := `---
+profiles:
+  keep:
+    utf8-bom: keep
+  forget:
+    utf8-bom: forget
+  add:
+    utf8-bom: ADD
+  default:
+    source: .
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+	expected := map[string]ConfigBOM{
+		"keep":    ConfigKeepBOM,
+		"forget":  ConfigForgetBOM,
+		"add":     ConfigAddBOM,
+		"default": ConfigForgetBOM,
+	}
+	for name, bom := range expected {
+		require.NotNil(t, config.Profiles[name].BOM, name)
+		assert.Equal(t, bom, *config.Profiles[name].BOM, name)
+	}
+}
+
+func TestCleanupPreambleExtensions(t *testing.T) {
+	config := Config{
+		Profiles: map[string]ConfigProfile{
+			"first": {
+				Preambles: map[string]*StringSlice{
+					"tpl":  {"^{{"},
+					".PHP": {"^<\\?"},
+					"*":    {"^#!"},
+				},
+			},
+		},
+	}
+	cleanupConfig(&config)
+	preambles := config.Profiles["first"].Preambles
+	assert.Len(t, preambles, 3)
+	assert.Contains(t, preambles, ".tpl")
+	assert.Contains(t, preambles, ".php")
+	assert.Contains(t, preambles, "*")
+}
+
+func TestLoadYearSource(t *testing.T) {
+	source := `---
+profiles:
+  clock:
+    year-source: clock
+  first:
+    year-source: Git-First-Commit
+  last:
+    year-source: git-last-commit
+  mtime:
+    year-source: mtime
+  default:
+    source: .
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+	expected := map[string]ConfigYearSource{
+		"clock":   ConfigYearFromClock,
+		"first":   ConfigYearFromGitFirstCommit,
+		"last":    ConfigYearFromGitLastCommit,
+		"mtime":   ConfigYearFromModTime,
+		"default": ConfigYearFromClock,
+	}
+	for name, yearSource := range expected {
+		require.NotNil(t, config.Profiles[name].YearSource, name)
+		assert.Equal(t, yearSource, *config.Profiles[name].YearSource, name)
+	}
+}
+
+func TestLoadVariables(t *testing.T) {
+	source := `---
+profiles:
+  self:
+    variables:
+      holder: TestCorp
+      license: MIT
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"holder": "TestCorp", "license": "MIT"}, config.Profiles["self"].Variables)
+}
+
+func TestLoadDefaultsAndExtends(t *testing.T) {
+	source := `---
+defaults:
+  copyright: copyright.txt
+  year: update
+  utf8-bom: keep
+  excludes: vendor
+  variables:
+    holder: TestCorp
+profiles:
+  base:
+    source: src
+    extensions: go
+    excludes: "*_gen.go"
+  child:
+    extends: base
+    extensions: js
+    year: leave
+    variables:
+      project: child
+  replaced:
+    extends: child
+    excludes: ["<replace>", node_modules]
+    copyright: other.txt
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+	require.Len(t, config.Profiles, 3)
+
+	base := config.Profiles["base"]
+	assert.Equal(t, "copyright.txt", base.Copyright)
+	assert.Equal(t, ConfigUpdateYear, *base.Year)
+	assert.Equal(t, ConfigKeepBOM, *base.BOM)
+	assert.Equal(t, StringSlice{"vendor", "*_gen.go"}, *base.Excludes)
+	assert.Equal(t, map[string]string{"holder": "TestCorp"}, base.Variables)
+
+	child := config.Profiles["child"]
+	assert.Equal(t, "copyright.txt", child.Copyright)
+	assert.Equal(t, ConfigLeaveYear, *child.Year)
+	assert.Equal(t, StringSlice{"src"}, *child.Source)
+	assert.Equal(t, StringSlice{".go", ".js"}, *child.Extensions)
+	// the defaults are only inherited once
+	assert.Equal(t, StringSlice{"vendor", "*_gen.go"}, *child.Excludes)
+	assert.Equal(t, map[string]string{"holder": "TestCorp", "project": "child"}, child.Variables)
+
+	replaced := config.Profiles["replaced"]
+	assert.Equal(t, "other.txt", replaced.Copyright)
+	assert.Equal(t, StringSlice{"node_modules"}, *replaced.Excludes)
+	assert.Equal(t, StringSlice{".go", ".js"}, *replaced.Extensions)
+}
+
+func TestInheritBooleanOptions(t *testing.T) {
+	source := `---
+defaults:
+  exclude-gitignore: true
+  commit-changes: true
+profiles:
+  inherited:
+    source: src
+  disabled:
+    extends: inherited
+    exclude-gitignore: false
+  none:
+    extends: disabled
+    commit-changes: no
+`
+	config, err := LoadConfig(bytes.NewBufferString(source))
+	require.NoError(t, err)
+
+	assert.True(t, isTrue(config.Profiles["inherited"].ExcludeFromGitIgnore))
+	assert.True(t, isTrue(config.Profiles["inherited"].CommitChanges))
+	assert.False(t, isTrue(config.Profiles["disabled"].ExcludeFromGitIgnore))
+	assert.True(t, isTrue(config.Profiles["disabled"].CommitChanges))
+	assert.False(t, isTrue(config.Profiles["none"].ExcludeFromGitIgnore))
+	assert.False(t, isTrue(config.Profiles["none"].CommitChanges))
+}
+
+func TestLoadExtendsErrors(t *testing.T) {
+	sources := []string{
+		"profiles:\n  first:\n    extends: second\n  second:\n    extends: first\n",
+		"profiles:\n  self:\n    extends: self\n",
+		"profiles:\n  first:\n    extends: unknown\n",
+		"defaults:\n  extends: first\nprofiles:\n  first:\n    source: .\n",
+	}
+	for _, source := range sources {
+		_, err := LoadConfig(bytes.NewBufferString(source))
+		assert.Error(t, err, source)
+	}
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"copyright-notice.yaml": "include: shared/profiles.yaml\nprofiles:\n  main:\n    extends: shared\n    source: src\n  overridden:\n    source: main\n",
+		"shared/profiles.yaml":  "include: more.yaml\nprofiles:\n  shared:\n    extensions: go\n    copyright: shared.txt\n  overridden:\n    source: shared\n",
+		"shared/more.yaml":      "profiles:\n  more:\n    source: more\n",
+	})
+	config, err := LoadFileConfig(filepath.Join(dir, "copyright-notice.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, config.Profiles, 4)
+	assert.Equal(t, "shared.txt", config.Profiles["main"].Copyright)
+	assert.Equal(t, StringSlice{".go"}, *config.Profiles["main"].Extensions)
+	assert.Equal(t, StringSlice{"main"}, *config.Profiles["overridden"].Source)
+	assert.Equal(t, StringSlice{"more"}, *config.Profiles["more"].Source)
+}
+
+func TestLoadIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"cycle.yaml":        "include: nested/other.yaml\n",
+		"nested/other.yaml": "include: ../cycle.yaml\n",
+		"missing.yaml":      "include: not-found.yaml\n",
+		"invalid.yaml":      "include: broken.yaml\n",
+		"broken.yaml":       "profiles: [\n",
+	})
+	for _, name := range []string{"cycle.yaml", "missing.yaml", "invalid.yaml"} {
+		_, err := LoadFileConfig(filepath.Join(dir, name))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestProfilesOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"copyright-notice.yaml": "include: [second.yaml, first.yaml]\nprofiles:\n  zulu:\n  alpha:\n  mike:\n",
+		"first.yaml":            "profiles:\n  first-b:\n  first-a:\n  zulu:\n",
+		"second.yaml":           "profiles:\n  second:\n",
+	})
+	config, err := LoadFileConfig(filepath.Join(dir, "copyright-notice.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zulu", "alpha", "mike", "second", "first-b", "first-a"}, config.ProfileNames())
+}
+
+func TestProfileNamesWithoutOrder(t *testing.T) {
+	config := Config{Profiles: map[string]ConfigProfile{"b": {}, "c": {}, "a": {}}}
+	assert.Equal(t, []string{"a", "b", "c"}, config.ProfileNames())
+}
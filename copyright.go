@@ -3,25 +3,76 @@
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
 	"regexp"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+
+	"github.com/creativeprojects/clog"
 )
 
 const (
-	magicYear  = "###Year#From#Template#Here###"
-	yearRegexp = `([\d]{4})`
+	magicYear      = "###Year#From#Template#Here###"
+	magicFirstYear = "###FirstYear#From#Template#Here###"
+	magicYearRange = "###YearRange#From#Template#Here###"
+	magicPath      = "###Path#From#Template#Here###"
+	magicName      = "###Name#From#Template#Here###"
+	magicExtension = "###Extension#From#Template#Here###"
+	magicLanguage  = "###Language#From#Template#Here###"
+	magicProfile   = "###Profile#From#Template#Here###"
+	magicSPDX      = "###SPDX#From#Template#Here###"
+	magicVariable  = "###Variable#%d#From#Template#Here###"
+	// yearExpression matches "2020", "2015-2020" or a list like "2015, 2018-2020"
+	yearExpression = `[\d]{4}(?:[ \t]*-[ \t]*[\d]{4})?(?:,[ \t]*[\d]{4}(?:[ \t]*-[ \t]*[\d]{4})?)*`
+	yearGroupName  = "year"
+	yearRegexp     = `(?P<` + yearGroupName + `>` + yearExpression + `)`
+	// variableRegexp matches any value of a variable on its line
+	variableRegexp = `([^\r\n]*?)`
+	// endOfLineVariableRegexp matches the value of a variable at the end of its line
+	endOfLineVariableRegexp = `([^\r\n]*)`
 )
 
-// CopyrightData contains copyright template data.
-// maybe we can add fields coming from the YAML configuration file?
+// CopyrightData contains copyright template data
 type CopyrightData struct {
-	Year int
+	FirstYear int
+	Year      int
+	Path      string            // path of the file relative to the source directory, with forward slashes
+	Name      string            // file name
+	Extension string            // extension of the file name, with the dot
+	Language  string            // language detected from the file extension
+	Profile   string            // name of the profile
+	SPDX      string            // SPDX license expression of the profile
+	Vars      map[string]string // variables of the profile
+}
+
+// newFileData returns the template data of the file: the years are not set
+func newFileData(path, profile string, variables map[string]string) CopyrightData {
+	extension := filepath.Ext(path)
+	return CopyrightData{
+		Path:      path,
+		Name:      filepath.Base(path),
+		Extension: extension,
+		Language:  getLanguage(strings.ToLower(extension)),
+		Profile:   profile,
+		Vars:      variables,
+	}
+}
+
+// YearRange returns "FirstYear-Year", or only "Year" when both years are the same
+func (d CopyrightData) YearRange() string {
+	if d.FirstYear == 0 || d.FirstYear >= d.Year {
+		return strconv.Itoa(d.Year)
+	}
+	return strconv.Itoa(d.FirstYear) + "-" + strconv.Itoa(d.Year)
 }
 
 type CopyrightTemplate struct {
-	tmpl *template.Template
+	tmpl      *template.Template
+	comment   *CommentStyle
+	profile   string
+	variables map[string]string
 }
 
 func ParseCopyrightTemplateFromFile(filename string) (*CopyrightTemplate, error) {
@@ -30,12 +81,12 @@ func ParseCopyrightTemplateFromFile(filename string) (*CopyrightTemplate, error)
 		return nil, err
 	}
 	return &CopyrightTemplate{
-		tmpl: tmpl,
+		tmpl: tmpl.Option("missingkey=error"),
 	}, nil
 }
 
 func ParseCopyrightTemplateFromString(raw string) (*CopyrightTemplate, error) {
-	tmpl := template.New("copyright")
+	tmpl := template.New("copyright").Option("missingkey=error")
 	_, err := tmpl.Parse(raw)
 	if err != nil {
 		return nil, err
@@ -45,6 +96,35 @@ func ParseCopyrightTemplateFromString(raw string) (*CopyrightTemplate, error) {
 	}, nil
 }
 
+// WithCommentStyle returns a copy of the template which wraps the notice into a comment.
+// A nil comment style means the notice is used as is
+func (t *CopyrightTemplate) WithCommentStyle(comment *CommentStyle) *CopyrightTemplate {
+	return &CopyrightTemplate{
+		tmpl:      t.tmpl,
+		comment:   comment,
+		profile:   t.profile,
+		variables: t.variables,
+	}
+}
+
+// IsComment returns true when the template already contains its comment syntax, like the header of an older version
+// copied as is: it must not be wrapped into a comment style
+func (t *CopyrightTemplate) IsComment() bool {
+	text, err := t.WithCommentStyle(nil).getTextWithMagicValues()
+	return err == nil && isCommentText(text)
+}
+
+// WithVariables returns a copy of the template with the values of the profile and its variables,
+// which are part of the regexp detecting the notice
+func (t *CopyrightTemplate) WithVariables(profile string, variables map[string]string) *CopyrightTemplate {
+	return &CopyrightTemplate{
+		tmpl:      t.tmpl,
+		comment:   t.comment,
+		profile:   profile,
+		variables: variables,
+	}
+}
+
 // GetCopyrightNotice returns the copyright header with the template variables replaced by their values.
 // please note any UTF8 BOM at the start of the template is stripped from the output.
 func (t *CopyrightTemplate) GetCopyrightNotice(data interface{}) ([]byte, error) {
@@ -56,7 +136,10 @@ func (t *CopyrightTemplate) GetCopyrightNotice(data interface{}) ([]byte, error)
 	}
 	content := buffer.Bytes()
 	if hasUTF8BOM(content) {
-		return content[3:], nil
+		content = content[3:]
+	}
+	if t.comment != nil {
+		return []byte(t.comment.Wrap(string(content))), nil
 	}
 	return content, nil
 }
@@ -65,51 +148,190 @@ func (t *CopyrightTemplate) GetCopyrightNotice(data interface{}) ([]byte, error)
 //
 // The magic values does not contain any special regexp character
 func (t *CopyrightTemplate) getTextWithMagicValues() (string, error) {
+	variables := make(map[string]string, len(t.variables))
+	for index, name := range t.variableNames() {
+		variables[name] = fmt.Sprintf(magicVariable, index)
+	}
+	fakeData := map[string]interface{}{
+		"Year":      magicYear,
+		"FirstYear": magicFirstYear,
+		"YearRange": magicYearRange,
+		"Path":      magicPath,
+		"Name":      magicName,
+		"Extension": magicExtension,
+		"Language":  magicLanguage,
+		"Profile":   magicProfile,
+		"SPDX":      magicSPDX,
+		"Vars":      variables,
+	}
+	return t.execute(fakeData)
+}
 
-	fakeData := map[string]string{
-		"Year": magicYear,
+// getTextWithMagicYears will return the template filled in with the data, except for the years replaced by magic values
+func (t *CopyrightTemplate) getTextWithMagicYears(data *CopyrightData) (string, error) {
+	fakeData := map[string]interface{}{
+		"Year":      magicYear,
+		"FirstYear": magicFirstYear,
+		"YearRange": magicYearRange,
+		"Path":      data.Path,
+		"Name":      data.Name,
+		"Extension": data.Extension,
+		"Language":  data.Language,
+		"Profile":   data.Profile,
+		"SPDX":      data.SPDX,
+		"Vars":      data.Vars,
 	}
+	text, err := t.execute(fakeData)
+	if err != nil {
+		return "", err
+	}
+	return normalizeMagicYears(text), nil
+}
+
+func (t *CopyrightTemplate) execute(data map[string]interface{}) (string, error) {
 	// also use default buffer size to avoid unnecessary memory allocations
 	buffer := bytes.NewBuffer(make([]byte, 0, defaultBufferSize))
-	err := t.tmpl.Execute(buffer, &fakeData)
+	err := t.tmpl.Execute(buffer, data)
 	if err != nil {
 		return "", err
 	}
 	content := buffer.Bytes()
 	if hasUTF8BOM(content) {
-		return string(content[3:]), nil
+		content = content[3:]
+	}
+	if t.comment != nil {
+		return t.comment.Wrap(string(content)), nil
 	}
 	return string(content), nil
 }
 
+// variableNames returns the names of the variables, sorted
+func (t *CopyrightTemplate) variableNames() []string {
+	names := make([]string, 0, len(t.variables))
+	for name := range t.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// variableGroups returns the regexp capture group replacing the magic value of each variable.
+// Every variable matches any value on its line, so a notice is still recognized after a variable changed.
+// A different SPDX expression is reported separately
+func (t *CopyrightTemplate) variableGroups() map[string]string {
+	groups := map[string]string{
+		magicPath:      variableRegexp,
+		magicName:      variableRegexp,
+		magicExtension: variableRegexp,
+		magicLanguage:  variableRegexp,
+		magicSPDX:      variableRegexp,
+		magicProfile:   variableRegexp,
+	}
+	for index := range t.variableNames() {
+		groups[fmt.Sprintf(magicVariable, index)] = variableRegexp
+	}
+	return groups
+}
+
+// GetCopyrightNoticeWithoutYear returns the copyright header with the year removed from it.
+// The space in front of the year is also removed so "Copyright {{.Year}} Corp" becomes "Copyright Corp"
+func (t *CopyrightTemplate) GetCopyrightNoticeWithoutYear(data *CopyrightData) ([]byte, error) {
+	text, err := t.getTextWithMagicYears(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(removeMagicYear(text)), nil
+}
+
+// GetCopyrightNoticeWithYears returns the copyright header with the years replaced by the year expression,
+// like "2015, 2018-2020" coming from an existing header
+func (t *CopyrightTemplate) GetCopyrightNoticeWithYears(data *CopyrightData, expression string) ([]byte, error) {
+	text, err := t.getTextWithMagicYears(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.ReplaceAll(text, magicYearRange, expression)), nil
+}
+
 // GetRegexp returns a searchable version of the template
 func (t *CopyrightTemplate) GetRegexp() (*regexp.Regexp, error) {
 	pattern, err := t.getTextWithMagicValues()
 	if err != nil {
 		return nil, err
 	}
-	return convertTextToRegexp(pattern)
+	return convertTextToRegexp(normalizeMagicYears(pattern), t.variableGroups())
+}
+
+// GetRegexpWithoutYear returns a searchable version of the template rendered without a year
+func (t *CopyrightTemplate) GetRegexpWithoutYear() (*regexp.Regexp, error) {
+	pattern, err := t.getTextWithMagicValues()
+	if err != nil {
+		return nil, err
+	}
+	return convertTextToRegexp(removeMagicYear(normalizeMagicYears(pattern)), t.variableGroups())
 }
 
-func convertTextToRegexp(text string) (*regexp.Regexp, error) {
-	EOL := "\n"
-	if runtime.GOOS == "windows" {
-		EOL = "\r\n"
+// normalizeMagicYears replaces any combination of first year and year by a single year range:
+// "{{.FirstYear}}-{{.Year}}" will be matched the same way as "{{.YearRange}}"
+func normalizeMagicYears(text string) string {
+	for _, separator := range []string{"-", " - ", ", ", ","} {
+		text = strings.ReplaceAll(text, magicFirstYear+separator+magicYear, magicYearRange)
 	}
+	text = strings.ReplaceAll(text, magicFirstYear, magicYearRange)
+	return strings.ReplaceAll(text, magicYear, magicYearRange)
+}
+
+func removeMagicYear(text string) string {
+	text = strings.ReplaceAll(text, " "+magicYearRange, "")
+	return strings.ReplaceAll(text, magicYearRange, "")
+}
+
+// convertTextToRegexp escapes the text into a regexp. The magic value of each variable is replaced by its capture group,
+// and the year is captured in a group named "year"
+func convertTextToRegexp(text string, variables map[string]string) (*regexp.Regexp, error) {
+	// the rendered text has the end of lines of the template file, and the files can have either kind
+	text = strings.ReplaceAll(text, "\r\n", "\n")
 	escapeChars := []string{`\`, `^`, `$`, `.`, `|`, `?`, `*`, `+`, `(`, `)`, `[`, `]`, `{`, `}`}
 	for _, escapeChar := range escapeChars {
 		text = strings.ReplaceAll(text, escapeChar, `\`+escapeChar)
 	}
-	// put back any year into the template
-	text = strings.ReplaceAll(text, magicYear, yearRegexp)
 	// replace beginning of line by something more permissive
-	text = strings.ReplaceAll(text, EOL+" \\*", EOL+"[ \t]*\\*")
+	text = strings.ReplaceAll(text, "\n \\*", "\n[ \t]*\\*")
 	// replace end of line by something a bit more permissive
-	text = strings.ReplaceAll(text, EOL, `[\s]+`)
+	text = strings.ReplaceAll(text, "\n", `[\s]+`)
 	// quick hack for the case a file only has a header with no return at the end
-	if text[len(text)-1] == '+' {
-		text = text[:len(text)-1] + "*"
+	if strings.HasSuffix(text, `[\s]+`) {
+		for strings.HasSuffix(text, `[\s]+`) {
+			// the blank line following a comment
+			text = strings.TrimSuffix(text, `[\s]+`)
+		}
+		text += `[\s]*`
+	}
+	for magic, group := range variables {
+		if group == variableRegexp {
+			// a non-greedy group would match nothing before the end of the line
+			text = strings.ReplaceAll(text, magic+`[\s]`, endOfLineVariableRegexp+`[\s]`)
+		}
+		text = strings.ReplaceAll(text, magic, group)
 	}
-	fmt.Println(text)
+	// put back the year into the template: the text before the first year and the text after
+	// are captured too
+	if index := strings.Index(text, magicYearRange); index > -1 {
+		after := strings.ReplaceAll(text[index+len(magicYearRange):], magicYearRange, yearExpression)
+		text = "(" + text[:index] + ")" + yearRegexp + "(" + after + ")"
+	}
+	clog.Debugf("copyright regexp: %s", text)
 	return regexp.Compile(text)
 }
+
+// yearGroup returns the index of the capture group of the year in the pattern, or -1 if there's none.
+// The year of a custom pattern (detect-own) without a group named "year" is the second group
+func yearGroup(pattern *regexp.Regexp) int {
+	if index := pattern.SubexpIndex(yearGroupName); index > -1 {
+		return index
+	}
+	if pattern.NumSubexp() >= 2 {
+		return 2
+	}
+	return -1
+}
@@ -2,6 +2,9 @@
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -51,7 +54,7 @@ func TestConversionToRegexp(t *testing.T) {
 	for index, testItem := range testData {
 		text := testItem
 		t.Run(fmt.Sprintf("%d", index), func(t *testing.T) {
-			pattern, err := convertTextToRegexp(text)
+			pattern, err := convertTextToRegexp(text, nil)
 			require.NoError(t, err)
 			result := pattern.FindString(text)
 			assert.Equal(t, text, result)
@@ -67,3 +70,156 @@ func TestTemporaryTemplateForSearching(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, strings.Replace(raw, "{{ .Year }}", magicYear, 1), text)
 }
+
+func TestCopyrightYearRange(t *testing.T) {
+	testData := []struct {
+		data     CopyrightData
+		expected string
+	}{
+		{CopyrightData{Year: 2020}, "2020"},
+		{CopyrightData{FirstYear: 2020, Year: 2020}, "2020"},
+		{CopyrightData{FirstYear: 2015, Year: 2020}, "2015-2020"},
+	}
+	tmpl, err := ParseCopyrightTemplateFromString("Copyright {{ .YearRange }} TestCorp")
+	require.NoError(t, err)
+	for _, testItem := range testData {
+		content, err := tmpl.GetCopyrightNotice(&testItem.data)
+		require.NoError(t, err)
+		assert.Equal(t, "Copyright "+testItem.expected+" TestCorp", string(content))
+	}
+}
+
+func TestRegexpWithYearRange(t *testing.T) {
+	templates := []string{
+		"/* Copyright {{ .Year }} TestCorp */",
+		"/* Copyright {{ .YearRange }} TestCorp */",
+		"/* Copyright {{ .FirstYear }}-{{ .Year }} TestCorp */",
+	}
+	testData := []string{
+		"2020",
+		"2015-2020",
+		"2015 - 2020",
+		"2019, 2021-2023",
+		"2015, 2017, 2019-2020",
+	}
+	for _, raw := range templates {
+		tmpl, err := ParseCopyrightTemplateFromString(raw)
+		require.NoError(t, err)
+		pattern, err := tmpl.GetRegexp()
+		require.NoError(t, err)
+		for _, years := range testData {
+			match := pattern.FindStringSubmatch("/* Copyright " + years + " TestCorp */\n")
+			require.Len(t, match, 4, raw)
+			assert.Equal(t, years, match[2])
+		}
+	}
+}
+
+func TestRegexpWithCommentStyle(t *testing.T) {
+	tmpl, err := ParseCopyrightTemplateFromString("Copyright (c) {{ .Year }} *Some+Corp?\n\nAll Rights Reserved\n")
+	require.NoError(t, err)
+	for name, style := range commentStyles {
+		style := style
+		t.Run(name, func(t *testing.T) {
+			wrapped := tmpl.WithCommentStyle(&style)
+			content, err := wrapped.GetCopyrightNotice(&CopyrightData{Year: 2020})
+			require.NoError(t, err)
+			pattern, err := wrapped.GetRegexp()
+			require.NoError(t, err)
+			match := pattern.FindSubmatch(append(content, []byte("some code\n")...))
+			require.Len(t, match, 4)
+			assert.Equal(t, string(content), string(match[0]))
+			assert.Equal(t, "2020", string(match[2]))
+
+			// the blank line after the comment is optional, so is the end of line of a file with only the header
+			header := strings.TrimRight(string(content), "\n")
+			assert.True(t, pattern.MatchString(header+"\nsome code\n"))
+			assert.True(t, pattern.MatchString(header))
+		})
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	raw := "// {{ .Path }} ({{ .Language }}) - {{ .Profile }}\n// Copyright {{ .YearRange }} {{ .Vars.holder }}, licensed under {{ .Vars.license }}\n"
+	tmpl, err := ParseCopyrightTemplateFromString(raw)
+	require.NoError(t, err)
+	tmpl = tmpl.WithVariables("main", map[string]string{"holder": "Some+Corp", "license": "MIT"})
+
+	data := newFileData("pkg/file.go", "main", map[string]string{"holder": "Some+Corp", "license": "MIT"})
+	assert.Equal(t, "file.go", data.Name)
+	assert.Equal(t, ".go", data.Extension)
+	data.FirstYear = 2015
+	data.Year = 2020
+	content, err := tmpl.GetCopyrightNotice(&data)
+	require.NoError(t, err)
+	assert.Equal(t, "// pkg/file.go (Go) - main\n// Copyright 2015-2020 Some+Corp, licensed under MIT\n", string(content))
+
+	content, err = tmpl.GetCopyrightNoticeWithoutYear(&data)
+	require.NoError(t, err)
+	assert.Equal(t, "// pkg/file.go (Go) - main\n// Copyright Some+Corp, licensed under MIT\n", string(content))
+
+	content, err = tmpl.GetCopyrightNoticeWithYears(&data, "2015, 2019")
+	require.NoError(t, err)
+	assert.Equal(t, "// pkg/file.go (Go) - main\n// Copyright 2015, 2019 Some+Corp, licensed under MIT\n", string(content))
+
+	pattern, err := tmpl.GetRegexp()
+	require.NoError(t, err)
+	// the variables depending on the file match the notice of any file
+	match := pattern.FindStringSubmatch("// other/script.py (Python) - main\n// Copyright 2018 Some+Corp, licensed under MIT\n")
+	require.NotNil(t, match)
+	assert.Equal(t, "2018", match[yearGroup(pattern)])
+	assert.Contains(t, match, "other/script.py")
+	assert.Contains(t, match, "Python")
+	assert.Contains(t, match, "MIT")
+
+	// and so do the variables of the profile, after their value changed
+	match = pattern.FindStringSubmatch("// other/script.py (Python) - other\n// Copyright 2018 Other Corp, licensed under Apache-2.0\n")
+	require.NotNil(t, match)
+	assert.Contains(t, match, "other")
+	assert.Contains(t, match, "Other Corp")
+	assert.Contains(t, match, "Apache-2.0")
+
+	pattern, err = tmpl.GetRegexpWithoutYear()
+	require.NoError(t, err)
+	assert.True(t, pattern.MatchString("// file.js (JavaScript) - main\n// Copyright Some+Corp, licensed under MIT\n"))
+}
+
+func TestNoticeWithChangedVariable(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("/*\n * Copyright (C) {{.Year}} {{.Vars.holder}}. All rights reserved.\n */\n"), 0600))
+	profile := ConfigProfile{
+		Copyright: copyright,
+		Variables: map[string]string{"holder": "New Corp"},
+		Year:      yearPolicy(ConfigLeaveYear),
+	}
+	// the notice was added when the holder had another name
+	content := "/*\n * Copyright (C) 2015 Old Corp. All rights reserved.\n */\n" + testSource
+	result, results := checkTestFile(t, profile, content)
+	assert.Equal(t, content, result)
+	assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+}
+
+func TestTemplateUnknownVariable(t *testing.T) {
+	tmpl, err := ParseCopyrightTemplateFromString("Copyright {{ .Year }} {{ .Vars.holder }}\n")
+	require.NoError(t, err)
+	_, err = tmpl.GetRegexp()
+	assert.Error(t, err)
+	_, err = tmpl.WithVariables("main", map[string]string{"holder": "TestCorp"}).GetRegexp()
+	assert.NoError(t, err)
+}
+
+func TestYearGroup(t *testing.T) {
+	testData := []struct {
+		pattern string
+		group   int
+	}{
+		{`Copyright`, -1},
+		{`(Copyright) (\d{4})`, 2},
+		{`^([ \t\r\n]*/\*[ \t\r\n*]*Copyright[ \t]+\(C\)[ \t]+)([\d]{4})([ \t]+CreativeProjects\.[ \t]*[\r\n]+)`, 2},
+		{`(\w+) (Copyright) ` + yearRegexp, 3},
+	}
+	for _, testItem := range testData {
+		assert.Equal(t, testItem.group, yearGroup(regexp.MustCompile(testItem.pattern)), testItem.pattern)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	diffContextLines = 3
+)
+
+var (
+	// diffRoots is the root of the git repository of each current directory
+	diffRoots      = make(map[string]string)
+	diffRootsMutex sync.Mutex
+)
+
+// unifiedDiff returns the differences between the content before and after the changes, in the unified format accepted by "git apply".
+// The changes made by this tool are always in one block of the file, so the diff only contains one hunk
+func unifiedDiff(fileName string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+	linesBefore := splitLines(before)
+	linesAfter := splitLines(after)
+
+	// number of identical lines at the start and at the end of both versions
+	prefix := 0
+	for prefix < len(linesBefore) && prefix < len(linesAfter) && linesBefore[prefix] == linesAfter[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesBefore)-prefix && suffix < len(linesAfter)-prefix &&
+		linesBefore[len(linesBefore)-1-suffix] == linesAfter[len(linesAfter)-1-suffix] {
+		suffix++
+	}
+
+	start := max(prefix-diffContextLines, 0)
+	endBefore := min(len(linesBefore)-suffix+diffContextLines, len(linesBefore))
+	endAfter := min(len(linesAfter)-suffix+diffContextLines, len(linesAfter))
+
+	name := diffPath(fileName)
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", name, name, name, name)
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(start, endBefore-start), hunkRange(start, endAfter-start))
+	for _, line := range linesBefore[start:prefix] {
+		writeDiffLine(builder, ' ', line)
+	}
+	for _, line := range linesBefore[prefix : len(linesBefore)-suffix] {
+		writeDiffLine(builder, '-', line)
+	}
+	for _, line := range linesAfter[prefix : len(linesAfter)-suffix] {
+		writeDiffLine(builder, '+', line)
+	}
+	for _, line := range linesBefore[len(linesBefore)-suffix : endBefore] {
+		writeDiffLine(builder, ' ', line)
+	}
+	return builder.String()
+}
+
+// diffPath returns the path of the file in the diff, where "git apply" looks for it: relative to the root
+// of the git repository of the current directory, or to the current directory outside of a repository
+func diffPath(fileName string) string {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return strings.TrimPrefix(filepath.ToSlash(fileName), "./")
+	}
+	diffRootsMutex.Lock()
+	root, found := diffRoots[currentDir]
+	if !found {
+		// empty when the current directory is not inside a repository
+		root, _ = gitRoot(currentDir)
+		diffRoots[currentDir] = root
+	}
+	diffRootsMutex.Unlock()
+
+	if root != "" {
+		relative, err := gitRelativePath(root, fileName)
+		if err == nil && !strings.HasPrefix(relative, "../") {
+			return relative
+		}
+	}
+	relative, err := filepath.Rel(currentDir, absolutePath(fileName))
+	if err != nil {
+		return filepath.ToSlash(fileName)
+	}
+	return filepath.ToSlash(relative)
+}
+
+// writeDiff saves the diff into the file (or to the console when the file name is empty or "-")
+func writeDiff(diff, filename string) error {
+	if filename == "" || filename == "-" {
+		_, err := os.Stdout.WriteString(diff)
+		return err
+	}
+	return os.WriteFile(filename, []byte(diff), 0666)
+}
+
+// splitLines returns the lines with their end of line
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hunkRange returns the range of lines of a hunk: the first line starts at 1, unless the range is empty
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func writeDiffLine(builder *strings.Builder, operation byte, line string) {
+	builder.WriteByte(operation)
+	builder.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		builder.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testData := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			"no change",
+			"line\n",
+			"line\n",
+			"",
+		},
+		{
+			"insert at the top",
+			"1\n2\n3\n4\n5\n",
+			"header\n1\n2\n3\n4\n5\n",
+			"@@ -1,3 +1,4 @@\n+header\n 1\n 2\n 3\n",
+		},
+		{
+			"insert after preamble",
+			"#!/bin/sh\n1\n2\n3\n4\n5\n",
+			"#!/bin/sh\nheader\n1\n2\n3\n4\n5\n",
+			"@@ -1,4 +1,5 @@\n #!/bin/sh\n+header\n 1\n 2\n 3\n",
+		},
+		{
+			"replace a line",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"no end of line",
+			"1",
+			"header\n1",
+			"@@ -1 +1,2 @@\n+header\n 1\n\\ No newline at end of file\n",
+		},
+		{
+			"empty file",
+			"",
+			"header\n",
+			"@@ -0,0 +1 @@\n+header\n",
+		},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.name, func(t *testing.T) {
+			diff := unifiedDiff("./dir/file.go", []byte(testItem.before), []byte(testItem.after))
+			if testItem.expected == "" {
+				assert.Empty(t, diff)
+				return
+			}
+			assert.Equal(t, "diff --git a/dir/file.go b/dir/file.go\n--- a/dir/file.go\n+++ b/dir/file.go\n"+testItem.expected, diff)
+		})
+	}
+}
+
+func TestUnifiedDiffWithGitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	testData := []struct {
+		before string
+		after  string
+	}{
+		{"1\n2\n3\n4\n5\n", "header\n1\n2\n3\n4\n5\n"},
+		{"#!/bin/sh\necho", "#!/bin/sh\n# header\necho"},
+		{"#!/bin/sh", "#!/bin/sh\n# header\n"},
+		{"\xef\xbb\xbf/* 2015 */\ncode\n", "/* 2015-2020 */\ncode\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"},
+	}
+	for _, testItem := range testData {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte(testItem.before), 0600))
+		patch := filepath.Join(dir, "changes.patch")
+		require.NoError(t, writeDiff(unifiedDiff("file.txt", []byte(testItem.before), []byte(testItem.after)), patch))
+
+		cmd := exec.Command("git", "apply", "changes.patch")
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+
+		content, err := os.ReadFile(filepath.Join(dir, "file.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, testItem.after, string(content))
+	}
+}
+
+func TestDiffWithAbsoluteSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	currentDir, err := os.Getwd()
+	require.NoError(t, err)
+	defer os.Chdir(currentDir)
+
+	repository := initGitRepository(t)
+	for _, dir := range []string{filepath.Join(repository, "src"), t.TempDir()} {
+		writeTestTree(t, dir, map[string]string{
+			"sub/main.go":   testSource,
+			"copyright.txt": testTemplate,
+		})
+		config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    copyright: " + filepath.Join(dir, "copyright.txt") + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0600))
+		require.NoError(t, os.Chdir(dir))
+
+		exitCode := runCommand(t, "--config", "config.yaml", "--dry-run", "--diff", "--diff-file", "changes.patch")
+		assert.Equal(t, 0, exitCode)
+		patch, err := os.ReadFile("changes.patch")
+		require.NoError(t, err)
+		assert.NotContains(t, string(patch), dir)
+
+		cmd := exec.Command("git", "apply", "changes.patch")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		content, err := os.ReadFile(filepath.Join(dir, "sub", "main.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "Copyright (C)")
+	}
+}
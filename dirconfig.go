@@ -0,0 +1,195 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"gopkg.in/yaml.v2"
+)
+
+// directoryConfigName is the name of the configuration file overriding the profiles for a directory and its sub-directories
+const directoryConfigName = ".copyright-notice.yaml"
+
+// directoryConfig is the content of a directory configuration file: the fields of a profile, and a root marker
+type directoryConfig struct {
+	Root          bool `yaml:"root"` // don't load the configuration files of the parent directories
+	ConfigProfile `yaml:",inline"`
+}
+
+// directorySettings is the stack of directory configurations applying to a directory, from the lowest to the highest precedence
+type directorySettings struct {
+	files      []string
+	profiles   []ConfigProfile
+	exclusions []*exclusion
+}
+
+// withDirectory returns a new stack with the configuration file found in the absolute directory added on top
+func (s *directorySettings) withDirectory(directory string) *directorySettings {
+	filename := filepath.Join(directory, directoryConfigName)
+	config, err := loadDirectoryConfig(filename)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			clog.Errorf("cannot load configuration file '%s': %s", filename, err)
+		}
+		return s
+	}
+	clog.Debugf("loading directory configuration from '%s'", filename)
+	settings := &directorySettings{}
+	if !config.Root {
+		settings.files = append(settings.files, s.files...)
+		settings.profiles = append(settings.profiles, s.profiles...)
+		settings.exclusions = append(settings.exclusions, s.exclusions...)
+	}
+	settings.files = append(settings.files, filename)
+	settings.profiles = append(settings.profiles, config.ConfigProfile)
+	if config.Excludes != nil {
+		// the patterns with a path are relative to the directory
+		patterns := make([]string, len(*config.Excludes))
+		for index, pattern := range *config.Excludes {
+			if strings.ContainsAny(pattern, `*/\`) && !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(directory, pattern)
+			}
+			patterns[index] = pattern
+		}
+		settings.exclusions = append(settings.exclusions, newExclusion(patterns...))
+	}
+	return settings
+}
+
+// loadDirectoryConfig loads a directory configuration file. The template files are relative to its directory
+func loadDirectoryConfig(filename string) (directoryConfig, error) {
+	config := directoryConfig{}
+	file, err := os.Open(filename)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+	err = yaml.NewDecoder(file).Decode(&config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return config, err
+	}
+	profile := &config.ConfigProfile
+	if profile.Extends != "" || profile.Source != nil || profile.Extensions != nil || profile.ExcludeFrom != "" ||
+		profile.ExcludeFromGitIgnore != nil || profile.CommitChanges != nil || profile.CommitMessage != "" || profile.CommitAuthor != "" || profile.Output != "" {
+		clog.Warningf("'%s': extends, source, extensions, exclude-from, exclude-gitignore, output and the commit options are ignored in a directory configuration", filename)
+	}
+	config.ConfigProfile = ConfigProfile{
+		Copyright:    relativeTemplate(filepath.Dir(filename), profile.Copyright),
+		Variables:    profile.Variables,
+		SPDX:         profile.SPDX,
+		CommentStyle: profile.CommentStyle,
+		Preambles:    profile.Preambles,
+		BOM:          profile.BOM,
+		Year:         profile.Year,
+		YearRange:    profile.YearRange,
+		YearSource:   profile.YearSource,
+		Excludes:     profile.Excludes,
+		DetectOwn:    profile.DetectOwn,
+		DetectOthers: profile.DetectOthers,
+	}
+	if profile.Replaces != nil {
+		replaces := make(StringSlice, len(*profile.Replaces))
+		for index, template := range *profile.Replaces {
+			replaces[index] = relativeTemplate(filepath.Dir(filename), template)
+		}
+		config.Replaces = &replaces
+	}
+	return config, nil
+}
+
+// relativeTemplate returns the path of a template file relative to the directory
+func relativeTemplate(directory, template string) string {
+	if template == "" || template == replaceMarker || isBuiltinTemplate(template) || filepath.IsAbs(template) {
+		return template
+	}
+	return filepath.Join(directory, template)
+}
+
+// key identifies the settings: the files with the same key share the same settings
+func (s *directorySettings) key() string {
+	return strings.Join(s.files, string(os.PathListSeparator))
+}
+
+// excluded returns true when the absolute path is excluded by a directory configuration
+func (s *directorySettings) excluded(path string) bool {
+	for _, exclusions := range s.exclusions {
+		if exclusions.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the profile overridden by the directory configurations
+func (s *directorySettings) apply(profile ConfigProfile) ConfigProfile {
+	for _, override := range s.profiles {
+		if override.Copyright != "" && override.CommentStyle == "" {
+			// a different template comes with its own comment style
+			profile.CommentStyle = ""
+		}
+		override.Excludes = nil
+		profile = override.inherit(profile)
+	}
+	return cleanupProfile(profile)
+}
+
+// directorySettingsCache keeps the settings of each absolute directory. It is not safe for concurrent use
+type directorySettingsCache map[string]*directorySettings
+
+// get returns the settings of the absolute directory, loading the configuration files of its parents first
+func (c directorySettingsCache) get(directory string) *directorySettings {
+	if settings, found := c[directory]; found {
+		return settings
+	}
+	parent := filepath.Dir(directory)
+	settings := &directorySettings{}
+	if parent != directory {
+		settings = c.get(parent)
+	}
+	settings = settings.withDirectory(directory)
+	c[directory] = settings
+	return settings
+}
+
+// loadDirectoryNotices prepares a notice for each directory settings of the files in the queue.
+// The files without any directory configuration keep the notice of the profile
+func (n Notice) loadDirectoryNotices(name string, profile ConfigProfile, fileQueue *list.List, settingsOf func(fileName string) *directorySettings) error {
+	notices := make(map[string]*Notice)
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		fileName := e.Value.(FileEntry).Name
+		settings := settingsOf(fileName)
+		if len(settings.files) == 0 {
+			continue
+		}
+		notice, found := notices[settings.key()]
+		if !found {
+			loaded, err := loadNotice(name, settings.apply(profile))
+			if err != nil {
+				return fmt.Errorf("invalid directory configuration '%s': %w", settings.files[len(settings.files)-1], err)
+			}
+			loaded.remove = n.remove
+			if loaded.years.source == n.years.source {
+				// share the history already read from git
+				loaded.years = n.years
+			}
+			notice = &loaded
+			notices[settings.key()] = notice
+		}
+		n.fileNotices[fileName] = notice
+	}
+	return nil
+}
+
+// forFile returns the notice of the file, which can be overridden by a directory configuration
+func (n Notice) forFile(fileName string) Notice {
+	if notice, found := n.fileNotices[fileName]; found {
+		return *notice
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorySettings(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		".copyright-notice.yaml":          "copyright: builtin:apache-2.0\nvariables:\n  holder: Acme\n  team: core\nyear: update\n",
+		"main.go":                         testSource,
+		"sdk/.copyright-notice.yaml":      "variables:\n  team: sdk\nexcludes: [generated/**, mock.go]\n",
+		"sdk/client/client.go":            testSource,
+		"internal/.copyright-notice.yaml": "root: true\ncopyright: ../proprietary.txt\nvariables:\n  holder: Acme Internal\n",
+		"internal/main.go":                testSource,
+		"broken/.copyright-notice.yaml":   "copyright: [\n",
+		"broken/main.go":                  testSource,
+	})
+	profile := cleanupProfile(ConfigProfile{
+		Copyright: "copyright.txt",
+		Variables: map[string]string{"holder": "Someone", "license": "MIT"},
+		Year:      yearPolicy(ConfigLeaveYear),
+	})
+	parser := NewParser([]string{".go"}, newExclusion())
+
+	settings := parser.Settings(filepath.Join(dir, "main.go"))
+	assert.Equal(t, []string{filepath.Join(dir, ".copyright-notice.yaml")}, settings.files)
+	effective := settings.apply(profile)
+	assert.Equal(t, "builtin:apache-2.0", effective.Copyright)
+	assert.Equal(t, commentStyleAuto, effective.CommentStyle)
+	assert.Equal(t, ConfigUpdateYear, *effective.Year)
+	assert.Equal(t, map[string]string{"holder": "Acme", "team": "core", "license": "MIT"}, effective.Variables)
+
+	effective = parser.Settings(filepath.Join(dir, "sdk", "client", "client.go")).apply(profile)
+	assert.Equal(t, "builtin:apache-2.0", effective.Copyright)
+	assert.Equal(t, map[string]string{"holder": "Acme", "team": "sdk", "license": "MIT"}, effective.Variables)
+
+	// root: the parent directories are ignored
+	settings = parser.Settings(filepath.Join(dir, "internal", "main.go"))
+	assert.Equal(t, []string{filepath.Join(dir, "internal", ".copyright-notice.yaml")}, settings.files)
+	effective = settings.apply(profile)
+	assert.Equal(t, filepath.Join(dir, "proprietary.txt"), effective.Copyright)
+	assert.Equal(t, "", effective.CommentStyle)
+	assert.Equal(t, ConfigLeaveYear, *effective.Year)
+	assert.Equal(t, map[string]string{"holder": "Acme Internal", "license": "MIT"}, effective.Variables)
+
+	// an invalid file is ignored
+	assert.Equal(t, settings.key(), parser.Settings(filepath.Join(dir, "internal", "main.go")).key())
+	assert.Equal(t, parser.Settings(filepath.Join(dir, "main.go")).key(), parser.Settings(filepath.Join(dir, "broken", "main.go")).key())
+}
+
+func TestDirectoryExclusions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"sdk/.copyright-notice.yaml": "excludes: [generated/**, mock.go]\n",
+		"sdk/client.go":              testSource,
+		"sdk/mock.go":                testSource,
+		"sdk/generated/types.go":     testSource,
+		"sdk/sub/mock.go":            testSource,
+		"generated/types.go":         testSource,
+		"mock.go":                    testSource,
+	})
+	fileQueue := NewParser([]string{".go"}, newExclusion()).Directories([]string{dir})
+	files := make([]string, 0, fileQueue.Len())
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		relative, err := filepath.Rel(dir, e.Value.(FileEntry).Name)
+		require.NoError(t, err)
+		files = append(files, filepath.ToSlash(relative))
+	}
+	assert.ElementsMatch(t, []string{"generated/types.go", "mock.go", "sdk/client.go"}, files)
+
+	fileQueue = NewParser([]string{".go"}, newExclusion()).Files([]string{dir}, []string{
+		filepath.Join(dir, "sdk", "generated", "types.go"),
+		filepath.Join(dir, "sdk", "client.go"),
+	})
+	require.Equal(t, 1, fileQueue.Len())
+	assert.Equal(t, filepath.Join(dir, "sdk", "client.go"), fileQueue.Front().Value.(FileEntry).Name)
+}
+
+func TestRunWithDirectoryConfiguration(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"src/main.go":                         testSource,
+		"src/sdk/.copyright-notice.yaml":      "copyright: builtin:apache-2.0\nexcludes: generated/**\n",
+		"src/sdk/client.go":                   testSource,
+		"src/sdk/generated/types.go":          testSource,
+		"src/internal/.copyright-notice.yaml": "copyright: builtin:proprietary\nvariables:\n  holder: Acme Internal\n",
+		"src/internal/secret.go":              testSource,
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + filepath.Join(dir, "src") + "\n    extensions: go\n    year: update\n    copyright: builtin:mit\n    variables:\n      holder: Acme\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	assert.Equal(t, 0, runCommand(t, "--config", configFile))
+	expected := map[string]string{
+		"main.go":            "Copyright (c) " + currentYear + " Acme\n * SPDX-License-Identifier: MIT\n",
+		"sdk/client.go":      "Copyright " + currentYear + " Acme\n * SPDX-License-Identifier: Apache-2.0\n",
+		"internal/secret.go": "Copyright (c) " + currentYear + " Acme Internal. All rights reserved.\n",
+	}
+	for name, header := range expected {
+		content, err := os.ReadFile(filepath.Join(dir, "src", filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "Copyright"), name)
+		assert.True(t, strings.HasPrefix(string(content), "/*\n * "+header), name)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "src", "sdk", "generated", "types.go"))
+	require.NoError(t, err)
+	assert.Equal(t, testSource, string(content))
+
+	// each notice is recognized in its own directory
+	assert.Equal(t, 0, runCommand(t, "--config", configFile, commandCheck))
+}
+
+func TestInvalidDirectoryConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"copyright.txt":              testTemplate,
+		"main.go":                    testSource,
+		"sdk/.copyright-notice.yaml": "copyright: not-found.txt\n",
+		"sdk/client.go":              testSource,
+	})
+	profile := cleanupProfile(ConfigProfile{Copyright: filepath.Join(dir, "copyright.txt"), Extensions: &StringSlice{".go"}})
+	parser := NewParser(*profile.Extensions, newExclusion())
+	fileQueue := parser.Directories([]string{dir})
+	require.Equal(t, 2, fileQueue.Len())
+
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+	err = notice.loadDirectoryNotices("test", profile, fileQueue, parser.Settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "sdk", ".copyright-notice.yaml"))
+}
+
+func TestDirectoryNoticesShareYearResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"copyright.txt":               testTemplate,
+		"main.go":                     testSource,
+		"sdk/.copyright-notice.yaml":  "variables:\n  holder: Acme\n",
+		"sdk/client.go":               testSource,
+		"tool/.copyright-notice.yaml": "year-source: mtime\n",
+		"tool/main.go":                testSource,
+	})
+	profile := cleanupProfile(ConfigProfile{Copyright: filepath.Join(dir, "copyright.txt"), Extensions: &StringSlice{".go"}})
+	parser := NewParser(*profile.Extensions, newExclusion())
+	fileQueue := parser.Directories([]string{dir})
+	require.Equal(t, 3, fileQueue.Len())
+
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+	require.NoError(t, notice.loadDirectoryNotices("test", profile, fileQueue, parser.Settings))
+
+	sdk := notice.forFile(filepath.Join(dir, "sdk", "client.go"))
+	assert.Same(t, notice.years, sdk.years)
+	// a different year source needs its own resolver
+	tool := notice.forFile(filepath.Join(dir, "tool", "main.go"))
+	assert.NotSame(t, notice.years, tool.years)
+	assert.Equal(t, ConfigYearFromModTime, tool.years.source)
+}
@@ -66,21 +66,18 @@ func newSourceFileReader(reader *bufio.Reader, closer io.Closer) io.ReadCloser {
 func getFileReader(fileName string) (io.ReadCloser, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
-		progress(fileName, fileStatusCannotOpen, err)
 		return nil, err
 	}
 
 	buffer := bufio.NewReaderSize(file, defaultBufferSize)
 	bom, err := buffer.Peek(3)
 	if err != nil {
-		progress(fileName, fileStatusError, err)
 		return nil, err
 	}
 	if bom[0] == 0xef && bom[1] == 0xbb && bom[2] == 0xbf {
 		// This is a bom, move the file forward 3 positions
 		_, err := buffer.Discard(3)
 		if err != nil {
-			progress(fileName, fileStatusError, err)
 			return nil, err
 		}
 	}
@@ -90,7 +87,6 @@ func getFileReader(fileName string) (io.ReadCloser, error) {
 func getFileReaderFromPool(fileName string) (io.ReadCloser, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
-		progress(fileName, fileStatusCannotOpen, err)
 		return nil, err
 	}
 
@@ -98,14 +94,12 @@ func getFileReaderFromPool(fileName string) (io.ReadCloser, error) {
 	fileReader.Init(file)
 	bom, err := fileReader.reader.Peek(3)
 	if err != nil {
-		progress(fileName, fileStatusError, err)
 		return nil, err
 	}
 	if bom[0] == 0xef && bom[1] == 0xbb && bom[2] == 0xbf {
 		// This is a bom, move the file forward 3 positions
 		_, err := fileReader.reader.Discard(3)
 		if err != nil {
-			progress(fileName, fileStatusError, err)
 			return nil, err
 		}
 	}
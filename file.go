@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 )
 
@@ -30,6 +32,25 @@ func (f *File) Reset() *File {
 }
 
 func (f *File) Read(name string, size int64) error {
+	return f.read(name, size, func() (io.ReadCloser, error) {
+		return os.Open(name)
+	})
+}
+
+// ReadGitBlob reads the content of the git object instead of the file on disk.
+// This is used to read the version of a file staged in the index
+func (f *File) ReadGitBlob(name, blob string, size int64) error {
+	return f.read(name, size, func() (io.ReadCloser, error) {
+		cmd := exec.Command("git", "-C", filepath.Dir(name), "cat-file", "blob", blob)
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read git object %s: %w", blob, err)
+		}
+		return io.NopCloser(bytes.NewReader(output)), nil
+	})
+}
+
+func (f *File) read(name string, size int64, open func() (io.ReadCloser, error)) error {
 	const maxInt = 2147483647
 	if f.ready {
 		// clear up the buffer first
@@ -54,7 +75,7 @@ func (f *File) Read(name string, size int64) error {
 	if f.size > cap(f.content) {
 		return NewError(FileErrorTooBig, fmt.Errorf("file size = %d bigger than buffer size = %d", f.size, cap(f.content)))
 	}
-	file, err := os.Open(f.name)
+	file, err := open()
 	if err != nil {
 		return NewError(FileErrorCannotOpen, err)
 	}
@@ -63,11 +84,11 @@ func (f *File) Read(name string, size int64) error {
 	// reslice the buffer
 	f.content = f.content[:f.size]
 	// and read the whole file
-	read, err := file.Read(f.content)
-	if err != nil && err != io.EOF {
+	read, err := io.ReadFull(file, f.content)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return NewError(FileErrorReading, err)
 	}
-	if err == io.EOF || read != f.size {
+	if read != f.size {
 		return NewError(FileErrorReading, fmt.Errorf("file size = %d bytes but read %d bytes instead", f.size, read))
 	}
 
@@ -83,6 +104,11 @@ func (f *File) HasUTF8BOM() bool {
 	return hasUTF8BOM(f.content)
 }
 
+// Raw returns the file content as it was read (with the UTF8 BOM if any)
+func (f *File) Raw() []byte {
+	return f.content
+}
+
 // Bytes returns the file content (with the UTF8 BOM stripped out if any)
 func (f *File) Bytes() []byte {
 	if f.HasUTF8BOM() {
@@ -91,15 +117,58 @@ func (f *File) Bytes() []byte {
 	return f.content
 }
 
-// AddHeader saves the file with the new header.
+// AddHeader saves the file with the new header inserted at the position (which is after the preamble of the file).
 // Instead of creating a file in place, it saves a temporary file then renames it
-func (f *File) AddHeader(header []byte, keepUTF8BOM bool) error {
+func (f *File) AddHeader(header []byte, position int, bom ConfigBOM) error {
+	return f.save(bom, f.headerParts(header, position)...)
+}
+
+// PreviewHeader returns the content of the file as it would be saved by AddHeader
+func (f *File) PreviewHeader(header []byte, position int, bom ConfigBOM) []byte {
+	return f.preview(bom, f.headerParts(header, position)...)
+}
+
+func (f *File) headerParts(header []byte, position int) [][]byte {
+	content := f.Bytes()
+	preamble := content[:position]
+	if len(preamble) > 0 && preamble[len(preamble)-1] != '\n' {
+		// the preamble is the whole file with no end of line
+		return [][]byte{preamble, []byte("\n"), header, content[position:]}
+	}
+	return [][]byte{preamble, header, content[position:]}
+}
+
+// Replace saves the file with a new content.
+// The new content should not start with a UTF8 BOM: it will be added according to the BOM option
+func (f *File) Replace(content []byte, bom ConfigBOM) error {
+	return f.save(bom, content)
+}
+
+// PreviewReplace returns the content of the file as it would be saved by Replace
+func (f *File) PreviewReplace(content []byte, bom ConfigBOM) []byte {
+	return f.preview(bom, content)
+}
+
+func (f *File) preview(bom ConfigBOM, parts ...[]byte) []byte {
+	buffer := bytes.NewBuffer(make([]byte, 0, len(f.content)+defaultBufferSize))
+	// writing into a bytes.Buffer never returns an error
+	_ = f.saveContent(buffer, bom, parts...)
+	return buffer.Bytes()
+}
+
+func (f *File) save(bom ConfigBOM, parts ...[]byte) error {
 	var err error
 	randomBytes := make([]byte, 10)
 	randomGenerator.Read(randomBytes)
 	tempFilename := filepath.Join(filepath.Dir(f.name), "$"+fmt.Sprintf("%x", randomBytes)+"$"+filepath.Base(f.name))
 
-	err = f.saveFile(tempFilename, header, keepUTF8BOM)
+	// keep the permissions of the original file (scripts need to stay executable)
+	var perm os.FileMode = 0666
+	if info, err := os.Stat(f.name); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	err = f.saveFile(tempFilename, perm, bom, parts...)
 	if err != nil {
 		return err
 	}
@@ -113,37 +182,37 @@ func (f *File) AddHeader(header []byte, keepUTF8BOM bool) error {
 	return nil
 }
 
-func (f *File) saveFile(filename string, header []byte, keepUTF8BOM bool) error {
-	outputFile, err := os.Create(filename)
+func (f *File) saveFile(filename string, perm os.FileMode, bom ConfigBOM, parts ...[]byte) error {
+	outputFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return err
 	}
 	defer outputFile.Close()
 
-	return f.saveContent(outputFile, header, keepUTF8BOM)
-}
+	// the permissions given to OpenFile are filtered by the umask
+	err = outputFile.Chmod(perm)
+	if err != nil {
+		return err
+	}
 
-func (f *File) saveContent(writer io.Writer, header []byte, keepUTF8BOM bool) error {
-	var err error
+	return f.saveContent(outputFile, bom, parts...)
+}
 
-	// Write the BOM if it was present
-	if keepUTF8BOM && f.HasUTF8BOM() {
+func (f *File) saveContent(writer io.Writer, bom ConfigBOM, parts ...[]byte) error {
+	// Write the BOM if it was present (or if we want one)
+	if bom.writeUTF8BOM(f.HasUTF8BOM()) {
 		_, err := writer.Write(UTF8BOM)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Write the copyright notice
-	_, err = writer.Write(header)
-	if err != nil {
-		return err
-	}
-
-	// Then write the file content
-	writer.Write(f.Bytes())
-	if err != nil {
-		return err
+	// Then write the copyright notice and the file content
+	for _, part := range parts {
+		_, err := writer.Write(part)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -94,3 +96,35 @@ func BenchmarkFileWithPoolOfBufferedFileReader(b *testing.B) {
 		pool.Put(reader)
 	}
 }
+
+// Compare the throughput of the analysis of 1000 files with a different number of workers.
+// Nothing is saved (dry-run) so each iteration analyzes the same files.
+func BenchmarkCheckForCopyrightNotices(b *testing.B) {
+	const count = 1000
+	dir := b.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	err := os.WriteFile(copyright, []byte(testTemplate), 0600)
+	if err != nil {
+		b.Fatal(err)
+	}
+	notice, err := loadNotice("test", ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigNoYear)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	fileQueue := createTestFiles(b, count)
+
+	dryRun := flags.dryRun
+	flags.dryRun = true
+	defer func() {
+		flags.dryRun = dryRun
+	}()
+
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("%d-workers", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				notice.checkForCopyrightNotices(fileQueue, workers)
+			}
+		})
+	}
+}
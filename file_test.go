@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -122,3 +125,55 @@ func TestFileReadWithBOM(t *testing.T) {
 		file.Reset()
 	}
 }
+
+func TestFileSaveContentWithBOM(t *testing.T) {
+	testData := []struct {
+		fileName string
+		bom      ConfigBOM
+		hasBOM   bool
+	}{
+		{"test_files/without_BOM.txt", ConfigForgetBOM, false},
+		{"test_files/without_BOM.txt", ConfigKeepBOM, false},
+		{"test_files/without_BOM.txt", ConfigAddBOM, true},
+		{"test_files/with_BOM.txt", ConfigForgetBOM, false},
+		{"test_files/with_BOM.txt", ConfigKeepBOM, true},
+		{"test_files/with_BOM.txt", ConfigAddBOM, true},
+	}
+	header := []byte("header\n")
+
+	for _, testItem := range testData {
+		info, err := os.Stat(testItem.fileName)
+		require.NoError(t, err)
+		file := NewFile(bufferSize)
+		require.NoError(t, file.Read(testItem.fileName, info.Size()))
+
+		buffer := &bytes.Buffer{}
+		err = file.saveContent(buffer, testItem.bom, header, file.Bytes())
+		require.NoError(t, err)
+
+		assert.Equal(t, testItem.hasBOM, hasUTF8BOM(buffer.Bytes()), testItem)
+		content := buffer.Bytes()
+		if testItem.hasBOM {
+			content = content[3:]
+		}
+		assert.Equal(t, append(header, file.Bytes()...), content)
+	}
+}
+
+func TestKeepFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable permission on Windows")
+	}
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "script.sh")
+	content := []byte("#!/bin/sh\necho hello\n")
+	require.NoError(t, os.WriteFile(fileName, content, 0755))
+
+	file := NewFile(bufferSize)
+	require.NoError(t, file.Read(fileName, int64(len(content))))
+	require.NoError(t, file.AddHeader([]byte("# Copyright\n"), len("#!/bin/sh\n"), ConfigForgetBOM))
+
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
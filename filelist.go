@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// readFileList reads a list of file names separated by new lines, or by null characters when there's any in the list.
+// The list is read from the standard input when the file name is "-"
+func readFileList(filename string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return parseFileList(content), nil
+}
+
+func parseFileList(content []byte) []string {
+	separator := "\n"
+	if bytes.IndexByte(content, 0) > -1 {
+		separator = "\x00"
+	}
+	files := make([]string, 0)
+	for _, file := range strings.Split(string(content), separator) {
+		if separator == "\n" {
+			file = strings.TrimSuffix(file, "\r")
+		}
+		if file == "" {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// fileSet is used to find the files of the list which were not selected by any profile
+type fileSet map[string]bool
+
+func newFileSet(files []string) fileSet {
+	set := make(fileSet, len(files))
+	for _, file := range files {
+		set[absolutePath(file)] = false
+	}
+	return set
+}
+
+// selected marks the file as selected by a profile
+func (s fileSet) selected(file string) {
+	s[absolutePath(file)] = true
+}
+
+// notSelected returns the files not selected by any profile, sorted
+func (s fileSet) notSelected() []string {
+	files := make([]string, 0)
+	for file, selected := range s {
+		if !selected {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func absolutePath(file string) string {
+	absolute, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return absolute
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileList(t *testing.T) {
+	testData := []struct {
+		content string
+		files   []string
+	}{
+		{"", []string{}},
+		{"\n", []string{}},
+		{"one.go", []string{"one.go"}},
+		{"one.go\ntwo.go\n", []string{"one.go", "two.go"}},
+		{"one.go\r\n\r\ndir/two.go\r\n", []string{"one.go", "dir/two.go"}},
+		{"one.go\x00with space.go\x00new\nline.go\x00", []string{"one.go", "with space.go", "new\nline.go"}},
+	}
+	for _, testItem := range testData {
+		assert.Equal(t, testItem.files, parseFileList([]byte(testItem.content)), testItem.content)
+	}
+}
+
+func TestFileSet(t *testing.T) {
+	set := newFileSet([]string{"one.go", "dir/../two.go", "three.go"})
+	set.selected("two.go")
+	set.selected(absolutePath("three.go"))
+	assert.Equal(t, []string{absolutePath("one.go")}, set.notSelected())
+}
+
+func TestParserFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"src/.gitignore":    "*_gen.go\n",
+		"src/main.go":       testSource,
+		"src/main_gen.go":   testSource,
+		"src/readme.txt":    testSource,
+		"src/vendor/lib.go": testSource,
+		"src/pkg/file.go":   testSource,
+		"src/pkg/empty.go":  "",
+		"other/outside.go":  testSource,
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(root, "src", "pkg", "subdir.go"), 0700))
+	source := filepath.Join(root, "src")
+	files := []string{
+		filepath.Join(root, "src", "main.go"),
+		filepath.Join(root, "src", "main_gen.go"),
+		filepath.Join(root, "src", "readme.txt"),
+		filepath.Join(root, "src", "vendor", "lib.go"),
+		filepath.Join(root, "src", "pkg", "..", "pkg", "file.go"),
+		filepath.Join(root, "src", "pkg", "empty.go"),
+		filepath.Join(root, "src", "pkg", "not-found.go"),
+		filepath.Join(root, "src", "pkg", "subdir.go"),
+		filepath.Join(root, "other", "outside.go"),
+		source,
+	}
+
+	parser := NewParser([]string{".go"}, newExclusion("vendor"))
+	assert.Equal(t, []string{"main.go", "main_gen.go", "pkg/file.go"}, queueFiles(t, source, parser.Files([]string{source}, files)))
+
+	parser = NewParser([]string{".go"}, newExclusion("vendor")).UseGitIgnore()
+	assert.Equal(t, []string{"main.go", "pkg/file.go"}, queueFiles(t, source, parser.Files([]string{source}, files)))
+}
+
+func TestRunWithFileList(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	writeTestTree(t, dir, map[string]string{
+		"go/selected.go":     testSource,
+		"go/not-selected.go": testSource,
+		"js/selected.js":     testSource,
+		"js/not-selected.js": testSource,
+		"other/file.go":      testSource,
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n" +
+		"  go:\n    source: " + filepath.Join(dir, "go") + "\n    extensions: go\n    copyright: " + copyright + "\n" +
+		"  js:\n    source: " + filepath.Join(dir, "js") + "\n    extensions: js\n    copyright: " + copyright + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", configFile, commandCheck,
+		filepath.Join(dir, "go", "selected.go"), filepath.Join(dir, "js", "selected.js"), filepath.Join(dir, "other", "file.go"))
+	assert.Equal(t, exitCodeMissingHeader, exitCode)
+
+	// the default command with a list of files
+	listFile := filepath.Join(dir, "files.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte(filepath.Join(dir, "go", "selected.go")+"\x00"+filepath.Join(dir, "js", "selected.js")+"\x00"), 0600))
+	exitCode = runCommand(t, "--config", configFile, "--files-from", listFile)
+	assert.Equal(t, 0, exitCode)
+
+	for name, changed := range map[string]bool{
+		"go/selected.go":     true,
+		"go/not-selected.go": false,
+		"js/selected.js":     true,
+		"js/not-selected.js": false,
+		"other/file.go":      false,
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, changed, string(content) != testSource, name)
+	}
+
+	// positional file names
+	exitCode = runCommand(t, "--config", configFile, filepath.Join(dir, "go", "not-selected.go"))
+	assert.Equal(t, 0, exitCode)
+	content, err := os.ReadFile(filepath.Join(dir, "go", "not-selected.go"))
+	require.NoError(t, err)
+	assert.NotEqual(t, testSource, string(content))
+
+	exitCode = runCommand(t, "--config", configFile, commandCheck, "--staged", filepath.Join(dir, "go", "selected.go"))
+	assert.Equal(t, exitCodeFailure, exitCode)
+	exitCode = runCommand(t, "--config", configFile, "--files-from", filepath.Join(dir, "not-found.txt"))
+	assert.Equal(t, exitCodeFailure, exitCode)
+}
@@ -4,4 +4,5 @@
 type FileEntry struct {
 	Name string
 	Size int64
+	Blob string // git object to read instead of the file (version staged in the index)
 }
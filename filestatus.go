@@ -7,14 +7,76 @@
 	fileStatusNoCopyright
 	fileStatusWithCopyright
 	fileStatusCopyrightYearNeedsUpdated
+	fileStatusHeaderReplaced
+	fileStatusHeaderRemoved
+	fileStatusWithoutCopyright
 	fileStatusCannotFindCopyrightYear
 	fileStatusAutoGenerated
 	fileStatusOtherCopyright
 	fileStatusTooBig
 	fileStatusCannotOpen
+	fileStatusMissingSPDX
+	fileStatusUnknownSPDX
+	fileStatusSPDXMismatch
 	fileStatusError // Keep this one last!
 )
 
+// allFileStatus is the list of all the status a file can have after analysis, in display order
+var allFileStatus = []fileStatus{
+	fileStatusNoCopyright,
+	fileStatusWithCopyright,
+	fileStatusCopyrightYearNeedsUpdated,
+	fileStatusHeaderReplaced,
+	fileStatusHeaderRemoved,
+	fileStatusWithoutCopyright,
+	fileStatusCannotFindCopyrightYear,
+	fileStatusAutoGenerated,
+	fileStatusOtherCopyright,
+	fileStatusTooBig,
+	fileStatusCannotOpen,
+	fileStatusMissingSPDX,
+	fileStatusUnknownSPDX,
+	fileStatusSPDXMismatch,
+	fileStatusError,
+}
+
+// Name is a short identifier of the status, used in reports
+func (f fileStatus) Name() string {
+	switch f {
+	case fileStatusNoCopyright:
+		return "no-copyright"
+	case fileStatusWithCopyright:
+		return "with-copyright"
+	case fileStatusCopyrightYearNeedsUpdated:
+		return "year-needs-update"
+	case fileStatusHeaderReplaced:
+		return "header-replaced"
+	case fileStatusHeaderRemoved:
+		return "header-removed"
+	case fileStatusWithoutCopyright:
+		return "without-copyright"
+	case fileStatusCannotFindCopyrightYear:
+		return "cannot-find-year"
+	case fileStatusAutoGenerated:
+		return "auto-generated"
+	case fileStatusOtherCopyright:
+		return "other-copyright"
+	case fileStatusTooBig:
+		return "too-big"
+	case fileStatusCannotOpen:
+		return "cannot-open"
+	case fileStatusMissingSPDX:
+		return "missing-spdx"
+	case fileStatusUnknownSPDX:
+		return "unknown-spdx"
+	case fileStatusSPDXMismatch:
+		return "spdx-mismatch"
+	case fileStatusError:
+		return "error"
+	}
+	return "unknown"
+}
+
 func (f fileStatus) String() string {
 	switch f {
 	case fileStatusNoCopyright:
@@ -23,6 +85,12 @@ func (f fileStatus) String() string {
 		return "found copyright data"
 	case fileStatusCopyrightYearNeedsUpdated:
 		return "copyright year needs updated"
+	case fileStatusHeaderReplaced:
+		return "outdated copyright header replaced"
+	case fileStatusHeaderRemoved:
+		return "copyright header removed"
+	case fileStatusWithoutCopyright:
+		return "no copyright header to remove"
 	case fileStatusCannotFindCopyrightYear:
 		return "cannot find a year in the copyright header"
 	case fileStatusAutoGenerated:
@@ -33,6 +101,12 @@ func (f fileStatus) String() string {
 		return "file is too big"
 	case fileStatusCannotOpen:
 		return "cannot open file"
+	case fileStatusMissingSPDX:
+		return "missing SPDX license identifier"
+	case fileStatusUnknownSPDX:
+		return "unknown SPDX license identifier"
+	case fileStatusSPDXMismatch:
+		return "SPDX license identifier different from the profile"
 	case fileStatusError:
 		return "general read/write error"
 	}
@@ -47,6 +121,12 @@ func (f fileStatus) Symbol() string {
 		return "."
 	case fileStatusCopyrightYearNeedsUpdated:
 		return "^"
+	case fileStatusHeaderReplaced:
+		return "~"
+	case fileStatusHeaderRemoved:
+		return "<"
+	case fileStatusWithoutCopyright:
+		return ","
 	case fileStatusCannotFindCopyrightYear:
 		return "Y"
 	case fileStatusAutoGenerated:
@@ -59,6 +139,12 @@ func (f fileStatus) Symbol() string {
 		return "!"
 	case fileStatusCannotOpen:
 		return "X"
+	case fileStatusMissingSPDX:
+		return "S"
+	case fileStatusUnknownSPDX:
+		return "U"
+	case fileStatusSPDXMismatch:
+		return "M"
 	case fileStatusUnknown:
 		return "?"
 	}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// envPrefix is the prefix of the environment variables setting the profile flags: COPYRIGHT_NOTICE_SOURCE
+	envPrefix = "COPYRIGHT_NOTICE_"
+	// flagsProfile is the name of the profile created from the flags when there's no configuration file
+	flagsProfile = "command-line"
+)
+
+// profileFlagNames are the flags overriding the fields of the profiles
+var profileFlagNames = []string{"source", "ext", "copyright", "exclude", "year", "utf8-bom", "detect-own", "var"}
+
+// envName returns the name of the environment variable of a flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// loadEnvironment sets the profile flags not given on the command line from their environment variable
+func loadEnvironment() error {
+	for _, name := range profileFlagNames {
+		value, found := os.LookupEnv(envName(name))
+		if !found || flag.CommandLine.Changed(name) {
+			continue
+		}
+		err := flag.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' in %s: %w", value, envName(name), err)
+		}
+	}
+	return nil
+}
+
+// hasProfile returns true when a field of the profiles is given on the command line or in the environment
+func (f Flags) hasProfile() bool {
+	return len(f.source) > 0 || len(f.extensions) > 0 || len(f.excludes) > 0 || len(f.variables) > 0 ||
+		f.copyright != "" || f.year != "" || f.bom != "" || f.detectOwn != ""
+}
+
+// profile returns the fields of the profiles set by the flags. The lists replace the lists of the profiles
+func (f Flags) profile() (ConfigProfile, error) {
+	profile := ConfigProfile{
+		Copyright: f.copyright,
+		DetectOwn: f.detectOwn,
+	}
+	if len(f.variables) > 0 {
+		profile.Variables = make(map[string]string, len(f.variables))
+		for _, variable := range f.variables {
+			name, value, found := strings.Cut(variable, "=")
+			if !found || strings.TrimSpace(name) == "" {
+				return profile, fmt.Errorf("invalid variable '%s' for --var: expected name=value", variable)
+			}
+			profile.Variables[strings.TrimSpace(name)] = value
+		}
+	}
+	profile.Source = replacingSlice(f.source)
+	profile.Extensions = replacingSlice(f.extensions)
+	profile.Excludes = replacingSlice(f.excludes)
+	if f.year != "" {
+		profile.Year = new(ConfigYear)
+		if err := decodeFlagValue("year", f.year, profile.Year); err != nil {
+			return profile, err
+		}
+	}
+	if f.bom != "" {
+		profile.BOM = new(ConfigBOM)
+		if err := decodeFlagValue("utf8-bom", f.bom, profile.BOM); err != nil {
+			return profile, err
+		}
+	}
+	return profile, nil
+}
+
+// replacingSlice returns a list replacing the inherited list, or nil when there's no value
+func replacingSlice(values []string) *StringSlice {
+	if len(values) == 0 {
+		return nil
+	}
+	list := append(StringSlice{replaceMarker}, values...)
+	return &list
+}
+
+// decodeFlagValue checks the value is one of the values accepted in the configuration, and decodes it like the configuration
+func decodeFlagValue(name, value string, target interface{}) error {
+	if !containsFold(configValues[name], value) {
+		return fmt.Errorf("invalid value '%s' for --%s: expected %s", value, name, strings.Join(configValues[name], ", "))
+	}
+	return yaml.Unmarshal([]byte(value), target)
+}
+
+// flagsConfig returns a configuration with a single profile on the current folder, to be completed by the flags
+func flagsConfig() Config {
+	config := NewConfig()
+	config.Profiles = map[string]ConfigProfile{
+		flagsProfile: {Source: &StringSlice{"."}},
+	}
+	return config
+}
+
+// overrideProfiles replaces the fields of the profiles by the ones set on the command line
+func overrideProfiles(config *Config, names []string, override ConfigProfile) {
+	for _, name := range names {
+		config.Profiles[name] = override.inherit(config.Profiles[name])
+	}
+	cleanupConfig(config)
+}
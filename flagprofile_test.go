@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagsProfile(t *testing.T) {
+	profile, err := Flags{
+		source:     []string{"src"},
+		extensions: []string{"go", "js"},
+		year:       "Update",
+		bom:        "keep",
+		variables:  []string{"holder=TestCorp", "team=a=b"},
+	}.profile()
+	require.NoError(t, err)
+	assert.Equal(t, StringSlice{replaceMarker, "src"}, *profile.Source)
+	assert.Equal(t, StringSlice{replaceMarker, "go", "js"}, *profile.Extensions)
+	assert.Nil(t, profile.Excludes)
+	assert.Equal(t, ConfigUpdateYear, *profile.Year)
+	assert.Equal(t, ConfigKeepBOM, *profile.BOM)
+	assert.Equal(t, map[string]string{"holder": "TestCorp", "team": "a=b"}, profile.Variables)
+
+	_, err = Flags{year: "sometimes"}.profile()
+	assert.Error(t, err)
+	_, err = Flags{bom: "drop"}.profile()
+	assert.Error(t, err)
+	_, err = Flags{variables: []string{"holder"}}.profile()
+	assert.Error(t, err)
+}
+
+func TestOverrideProfiles(t *testing.T) {
+	config, err := LoadConfig(strings.NewReader("profiles:\n  first:\n    source: src\n    extensions: c\n    copyright: first.txt\n" +
+		"    variables:\n      holder: Someone\n      team: core\n  second:\n    source: other\n    extensions: c\n"))
+	require.NoError(t, err)
+	override, err := Flags{extensions: []string{"go"}, year: "leave", variables: []string{"holder=TestCorp"}}.profile()
+	require.NoError(t, err)
+
+	overrideProfiles(&config, []string{"first"}, override)
+	first := config.Profiles["first"]
+	assert.Equal(t, StringSlice{"src"}, *first.Source)
+	assert.Equal(t, StringSlice{".go"}, *first.Extensions)
+	assert.Equal(t, "first.txt", first.Copyright)
+	assert.Equal(t, ConfigLeaveYear, *first.Year)
+	assert.Equal(t, map[string]string{"holder": "TestCorp", "team": "core"}, first.Variables)
+	// not selected
+	assert.Equal(t, StringSlice{".c"}, *config.Profiles["second"].Extensions)
+}
+
+func TestRunWithoutConfigFile(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"main.go":          testSource,
+		"vendor/vendor.go": testSource,
+		"script.py":        "print('hello')\n",
+	})
+	missing := filepath.Join(dir, "copyright-notice.yaml")
+
+	// no profile on the command line
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", missing))
+
+	assert.Equal(t, 0, runCommand(t, "--config", missing, "--source", dir, "--ext", "go", "--copyright", "builtin:mit",
+		"--var", "holder=TestCorp", "--year", "update", "--exclude", "vendor"))
+	content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "/*\n * Copyright (c) "+currentYear+" TestCorp\n"))
+	for _, name := range []string{"vendor/vendor.go", "script.py"} {
+		content, err = os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "Copyright", name)
+	}
+
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", missing, "--source", dir, "--ext", "go", "--year", "sometimes"))
+}
+
+func TestRunWithEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"main.go":       testSource,
+		"main.c":        testSource,
+		"copyright.txt": testTemplate,
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: c\n    copyright: " + filepath.Join(dir, "copyright.txt") + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	t.Setenv(envName("ext"), "go")
+	t.Setenv(envName("year"), "never")
+	// the command line takes precedence over the environment
+	assert.Equal(t, exitCodeMissingHeader, runCommand(t, "--config", configFile, "--year", "none", commandCheck))
+
+	t.Setenv(envName("year"), "none")
+	assert.Equal(t, 0, runCommand(t, "--config", configFile))
+	content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Copyright (C) TestCorp.")
+	content, err = os.ReadFile(filepath.Join(dir, "main.c"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "Copyright")
+
+	t.Setenv(envName("var"), "no-separator")
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", configFile))
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "COPYRIGHT_NOTICE_UTF8_BOM", envName("utf8-bom"))
+	assert.Equal(t, "COPYRIGHT_NOTICE_EXT", envName("ext"))
+}
@@ -1,15 +1,56 @@
 package main
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	flag "github.com/spf13/pflag"
 )
 
+const (
+	commandApply          = "apply"
+	commandCheck          = "check"
+	commandRemove         = "remove"
+	commandTemplates      = "templates"
+	commandValidateConfig = "validate-config"
+)
+
+// commands available from the command line, with their description
+var commands = map[string]string{
+	commandApply: "add the missing copyright notices and update the years (default command)",
+	commandCheck: "report the files with a missing or outdated copyright notice, without changing anything. " +
+		"The exit code is a combination of 2 (missing notice), 4 (outdated year), 8 (errors), 16 (outdated notice) and 32 (missing, unknown or mismatched SPDX license identifier)",
+	commandRemove: "remove our own copyright notices (the ones matching the template or detect-own) from the files",
+	commandTemplates: "list the built-in copyright templates (templates list) or display one of them (templates show apache-2.0). " +
+		"They are used with 'copyright: builtin:apache-2.0' and the 'holder' variable",
+	commandValidateConfig: "check the configuration file and the files it includes, and report each problem with its position",
+}
+
 type Flags struct {
 	verbose        bool
 	dryRun         bool
+	diff           bool
+	diffFile       string
 	configFile     string
 	outputFilename string
+	jobs           int
+	changedSince   string
+	staged         bool
+	filesFrom      string
+	reportFormat   string
+	reportFile     string
+	profiles       []string
 	help           bool
+	// profile fields given on the command line
+	source     []string
+	extensions []string
+	excludes   []string
+	copyright  string
+	year       string
+	bom        string
+	detectOwn  string
+	variables  []string
 }
 
 var (
@@ -19,7 +60,42 @@ type Flags struct {
 func init() {
 	flag.StringVarP(&flags.configFile, "config", "c", "copyright-notice.yaml", "Configuration file")
 	flag.BoolVar(&flags.dryRun, "dry-run", false, "Show all the files that would be processed, but don't save anything")
+	flag.BoolVar(&flags.diff, "diff", false, "Display the changes as a unified diff (can be used with --dry-run)")
+	flag.StringVar(&flags.diffFile, "diff-file", "", "Write the diff into a patch file instead of the console")
 	flag.BoolVarP(&flags.verbose, "verbose", "v", false, "Display more information")
 	flag.StringVarP(&flags.outputFilename, "output", "o", "", "Write the output into a file instead of the console")
+	flag.IntVarP(&flags.jobs, "jobs", "j", 0, "Number of files analyzed in parallel (default to the 'workers' configuration, or the number of CPUs)")
+	flag.StringVar(&flags.changedSince, "changed-since", "", "Only process the files added, modified or renamed since this git reference")
+	flag.BoolVar(&flags.staged, "staged", false, "Only process the files staged in git, using their version in the index (check command or --dry-run only)")
+	flag.StringVar(&flags.filesFrom, "files-from", "", "Only process the files listed in this file (or \"-\" for the standard input), separated by new lines or null characters")
+	flag.StringVar(&flags.reportFormat, "report-format", "", "Generate a report of all the files analyzed: json, junit or sarif, or an inventory of the third-party copyright headers in CSV: inventory")
+	flag.StringVar(&flags.reportFile, "report-file", "", "Write the report into a file instead of the console")
+	flag.StringArrayVarP(&flags.profiles, "profile", "p", nil, "Only run this profile (can be repeated, and accepts a glob pattern like 'frontend-*')")
 	flag.BoolVarP(&flags.help, "help", "h", false, "Prints usage")
+
+	// these flags override the profiles, or define a profile when there's no configuration file
+	flag.StringSliceVar(&flags.source, "source", nil, "Source folders (can be repeated or separated by commas, default to the current folder without configuration file)")
+	flag.StringSliceVar(&flags.extensions, "ext", nil, "File extensions to process (can be repeated or separated by commas)")
+	flag.StringVar(&flags.copyright, "copyright", "", "Copyright template file, or built-in template like 'builtin:mit'")
+	flag.StringSliceVar(&flags.excludes, "exclude", nil, "Files or folders to exclude (can be repeated or separated by commas)")
+	flag.StringVar(&flags.year, "year", "", "What to do with the year of the copyright notice: none, leave or update")
+	flag.StringVar(&flags.bom, "utf8-bom", "", "What to do with the UTF-8 BOM: forget, keep or add")
+	flag.StringVar(&flags.detectOwn, "detect-own", "", "Regexp detecting our own copyright notice")
+	flag.StringSliceVar(&flags.variables, "var", nil, "Template variable like 'holder=Acme Corp.' (can be repeated or separated by commas)")
+}
+
+func displayUsage() {
+	fmt.Print("\nUsage of copyright-notice:\n\n  copyright-notice [flags] [command] [files...]\n\nCommands:\n\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-10s %s\n", name, commands[name])
+	}
+	fmt.Print("\nFlags:\n\n")
+	flag.PrintDefaults()
+	fmt.Printf("\nEnvironment:\n\n  The flags %s can also be set with the environment variables %s<FLAG> (like %s)\n",
+		strings.Join(profileFlagNames, ", "), envPrefix, envName("utf8-bom"))
 }
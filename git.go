@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+)
+
+// gitRoot returns the root directory of the git repository containing the directory
+func gitRoot(directory string) (string, error) {
+	output, err := git(directory, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(output)), nil
+}
+
+// gitRelativePath returns the path of the file relative to the root of the repository, with forward slashes
+func gitRelativePath(root, file string) (string, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	// the root returned by git has its symbolic links resolved
+	directory, err := filepath.EvalSymlinks(filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+	relative, err := filepath.Rel(root, filepath.Join(directory, filepath.Base(file)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relative), nil
+}
+
+// git runs a git command in the directory and returns its output
+func git(directory string, args ...string) (string, error) {
+	clog.Debugf("running git %s", strings.Join(args, " "))
+	cmd := exec.Command("git", append([]string{"-C", directory}, args...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(output), nil
+}
+
+// gitChangedFiles returns the files added, modified or renamed in the directory, relative to the directory.
+// The changes are either the ones of the working tree since the reference, or the ones staged in the index
+func gitChangedFiles(directory, reference string, staged bool) ([]string, error) {
+	args := []string{"diff", "--name-only", "--relative", "-z", "--diff-filter=AMR"}
+	if staged {
+		args = append(args, "--cached")
+	} else {
+		if strings.HasPrefix(reference, "-") {
+			// it would be read as an option
+			return nil, fmt.Errorf("invalid git reference %q", reference)
+		}
+		args = append(args, reference, "--")
+	}
+	output, err := git(directory, args...)
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(output), nil
+}
+
+// gitStagedBlobs returns the object name of the version in the index of each file of the directory (relative to the directory)
+func gitStagedBlobs(directory string) (map[string]string, error) {
+	output, err := git(directory, "ls-files", "--stage", "-z")
+	if err != nil {
+		return nil, err
+	}
+	blobs := make(map[string]string)
+	for _, line := range splitNull(output) {
+		// <mode> <object> <stage>\t<file>
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(line[:tab])
+		if len(fields) < 3 {
+			continue
+		}
+		blobs[line[tab+1:]] = fields[1]
+	}
+	return blobs, nil
+}
+
+// gitBlobSize returns the size of the object
+func gitBlobSize(directory, blob string) (int64, error) {
+	output, err := git(directory, "cat-file", "-s", blob)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+}
+
+// splitNull splits a list separated by null characters
+func splitNull(output string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(output, "\x00") {
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueFiles returns the names of the files in the queue relative to the directory, sorted
+func queueFiles(t *testing.T, dir string, fileQueue *list.List) []string {
+	t.Helper()
+	files := make([]string, 0, fileQueue.Len())
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		relative, err := filepath.Rel(dir, e.Value.(FileEntry).Name)
+		require.NoError(t, err)
+		files = append(files, filepath.ToSlash(relative))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// createChangedRepository creates a repository with a first commit, then some changes committed, modified and staged
+func createChangedRepository(t *testing.T) string {
+	t.Helper()
+	dir := initGitRepository(t)
+	writeTestTree(t, dir, map[string]string{
+		".gitignore":         "*_gen.go\n",
+		"unchanged.go":       testSource,
+		"modified.go":        testSource,
+		"renamed.go":         testSource + "// renamed\n",
+		"deleted.go":         testSource,
+		"vendor/modified.go": testSource,
+		"src/modified.go":    testSource,
+		"src/modified.txt":   testSource,
+	})
+	_, err := git(dir, "add", ".")
+	require.NoError(t, err)
+	_, err = git(dir, "commit", "--quiet", "--message", "first commit")
+	require.NoError(t, err)
+	_, err = git(dir, "tag", "first")
+	require.NoError(t, err)
+
+	writeTestTree(t, dir, map[string]string{
+		"modified.go":        testSource + "\n",
+		"vendor/modified.go": testSource + "\n",
+		"src/modified.go":    testSource + "\n",
+		"src/modified.txt":   testSource + "\n",
+		"added.go":           testSource,
+		"added_gen.go":       testSource,
+	})
+	_, err = git(dir, "mv", "renamed.go", "new-name.go")
+	require.NoError(t, err)
+	_, err = git(dir, "rm", "--quiet", "deleted.go")
+	require.NoError(t, err)
+	_, err = git(dir, "add", "modified.go", "added.go", "vendor/modified.go")
+	require.NoError(t, err)
+	_, err = git(dir, "add", "--force", "added_gen.go")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := createChangedRepository(t)
+
+	files, err := gitChangedFiles(dir, "first", false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"added.go", "added_gen.go", "modified.go", "new-name.go", "src/modified.go", "src/modified.txt", "vendor/modified.go"}, files)
+
+	files, err = gitChangedFiles(dir, "", true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"added.go", "added_gen.go", "modified.go", "new-name.go", "vendor/modified.go"}, files)
+
+	// only the files of the directory, relative to the directory
+	files, err = gitChangedFiles(filepath.Join(dir, "src"), "first", false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"modified.go", "modified.txt"}, files)
+
+	_, err = gitChangedFiles(dir, "unknown-reference", false)
+	assert.Error(t, err)
+
+	// a reference cannot be an option
+	_, err = gitChangedFiles(dir, "--output=changed.txt", false)
+	assert.EqualError(t, err, `invalid git reference "--output=changed.txt"`)
+	assert.NoFileExists(t, filepath.Join(dir, "changed.txt"))
+}
+
+func TestParserChangedFiles(t *testing.T) {
+	dir := createChangedRepository(t)
+
+	parser := NewParser([]string{".go"}, newExclusion("vendor"))
+	assert.Equal(t, []string{"added.go", "added_gen.go", "modified.go", "new-name.go", "src/modified.go"}, queueFiles(t, dir, parser.ChangedFiles([]string{dir}, "first")))
+
+	parser = NewParser([]string{".go"}, newExclusion("vendor")).UseGitIgnore()
+	assert.Equal(t, []string{"added.go", "modified.go", "new-name.go", "src/modified.go"}, queueFiles(t, dir, parser.ChangedFiles([]string{dir}, "first")))
+
+	parser = NewParser([]string{".go"}, newExclusion()).UseGitIgnore()
+	assert.Equal(t, []string{"added.go", "modified.go", "new-name.go", "vendor/modified.go"}, queueFiles(t, dir, parser.StagedFiles([]string{dir})))
+}
+
+func TestParserStagedFilesReadFromIndex(t *testing.T) {
+	dir := createChangedRepository(t)
+	// the working tree is different from the index
+	writeTestTree(t, dir, map[string]string{"added.go": testSource + "\n\n\n"})
+
+	parser := NewParser([]string{".go"}, newExclusion())
+	fileQueue := parser.StagedFiles([]string{dir})
+	var entry FileEntry
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		if filepath.Base(e.Value.(FileEntry).Name) == "added.go" {
+			entry = e.Value.(FileEntry)
+		}
+	}
+	require.NotEmpty(t, entry.Blob)
+	assert.Equal(t, int64(len(testSource)), entry.Size)
+
+	file := NewFile(bufferSize)
+	require.NoError(t, file.ReadGitBlob(entry.Name, entry.Blob, entry.Size))
+	assert.Equal(t, testSource, string(file.Bytes()))
+}
+
+func TestCheckStagedFiles(t *testing.T) {
+	dir := initGitRepository(t)
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	header := "/*\n * Copyright (C) 2015 TestCorp.\n */\n"
+	writeTestTree(t, dir, map[string]string{
+		"staged.go":   header + testSource,
+		"unstaged.go": testSource,
+	})
+	_, err := git(dir, "add", "staged.go")
+	require.NoError(t, err)
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    year: leave\n    copyright: " + copyright + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", configFile, "--staged", commandCheck)
+	assert.Equal(t, 0, exitCode)
+
+	// the header is removed from the working tree but not from the index
+	writeTestTree(t, dir, map[string]string{"staged.go": testSource})
+	exitCode = runCommand(t, "--config", configFile, "--staged", commandCheck)
+	assert.Equal(t, 0, exitCode)
+
+	// now the version in the index has no header
+	_, err = git(dir, "add", "staged.go")
+	require.NoError(t, err)
+	exitCode = runCommand(t, "--config", configFile, "--staged", commandCheck)
+	assert.Equal(t, exitCodeMissingHeader, exitCode)
+
+	// staged files cannot be changed
+	exitCode = runCommand(t, "--config", configFile, "--staged")
+	assert.Equal(t, exitCodeFailure, exitCode)
+	exitCode = runCommand(t, "--config", configFile, "--staged", "--changed-since", "HEAD", commandCheck)
+	assert.Equal(t, exitCodeFailure, exitCode)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+)
+
+// names of the files containing exclusion patterns (in order of precedence) loaded in each directory
+var gitIgnoreFiles = []string{".gitignore", ".copyrightignore"}
+
+// ignoreRule is one line of a .gitignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreFile is the list of rules loaded from a file, relative to the directory of the file
+type ignoreFile struct {
+	base  string
+	rules []ignoreRule
+}
+
+// gitIgnore is the stack of ignore files applying to a directory, from the lowest to the highest precedence
+type gitIgnore struct {
+	files []*ignoreFile
+}
+
+// newGitIgnore loads the ignore files from the root of the git repository (if any) down to the parent of the directory.
+// The ignore files of the directory itself are loaded with withDirectory.
+func newGitIgnore(directory string) *gitIgnore {
+	ignore := &gitIgnore{}
+	directory, err := filepath.Abs(directory)
+	if err != nil || isGitRepository(directory) {
+		return ignore
+	}
+	// look for the root of the repository in the parent directories
+	parents := []string{}
+	for parent := filepath.Dir(directory); ; parent = filepath.Dir(parent) {
+		parents = append(parents, parent)
+		if isGitRepository(parent) {
+			break
+		}
+		if filepath.Dir(parent) == parent {
+			// reached the root of the file system: not inside a git repository
+			return ignore
+		}
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		ignore = ignore.withDirectory(parents[i])
+	}
+	return ignore
+}
+
+func isGitRepository(directory string) bool {
+	info, err := os.Stat(filepath.Join(directory, ".git"))
+	return err == nil && info.IsDir()
+}
+
+// withDirectory returns a new stack with the ignore files found in the absolute directory added on top
+func (g *gitIgnore) withDirectory(directory string) *gitIgnore {
+	files := make([]*ignoreFile, 0, len(g.files)+len(gitIgnoreFiles)+1)
+	files = append(files, g.files...)
+	names := gitIgnoreFiles
+	if isGitRepository(directory) {
+		// the exclude file of the repository has a lower precedence than the .gitignore files
+		names = append([]string{filepath.Join(".git", "info", "exclude")}, names...)
+	}
+	loaded := false
+	for _, name := range names {
+		lines, err := readLines(filepath.Join(directory, name))
+		if err != nil {
+			continue
+		}
+		clog.Debugf("loading exclusions from '%s'", filepath.Join(directory, name))
+		files = append(files, parseIgnoreLines(directory, lines))
+		loaded = true
+	}
+	if !loaded {
+		return g
+	}
+	return &gitIgnore{files: files}
+}
+
+// match returns true if the absolute path is excluded
+func (g *gitIgnore) match(path string, isDir bool) bool {
+	if isDir && filepath.Base(path) == ".git" {
+		return true
+	}
+	for i := len(g.files) - 1; i >= 0; i-- {
+		file := g.files[i]
+		relative, err := filepath.Rel(file.base, path)
+		if err != nil {
+			continue
+		}
+		relative = filepath.ToSlash(relative)
+		if relative == ".." || strings.HasPrefix(relative, "../") {
+			// outside of the directory of the .gitignore file
+			continue
+		}
+		// the last matching rule of a file has precedence
+		for j := len(file.rules) - 1; j >= 0; j-- {
+			rule := file.rules[j]
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.pattern.MatchString(relative) {
+				return !rule.negate
+			}
+		}
+	}
+	return false
+}
+
+// parseIgnoreLines converts the lines of a .gitignore file into rules
+func parseIgnoreLines(base string, lines []string) *ignoreFile {
+	file := &ignoreFile{
+		base:  base,
+		rules: make([]ignoreRule, 0, len(lines)),
+	}
+	for _, line := range lines {
+		rule, ok := parseIgnoreLine(line)
+		if !ok {
+			continue
+		}
+		file.rules = append(file.rules, rule)
+	}
+	return file
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	rule := ignoreRule{}
+	line = strings.TrimSuffix(line, "\r")
+	// trailing spaces are ignored unless they're escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	// a pattern with a slash at the beginning or in the middle is relative to the directory of the file
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expression := convertIgnorePattern(line)
+	if anchored {
+		expression = "^" + expression + "$"
+	} else {
+		expression = "^(?:.*/)?" + expression + "$"
+	}
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		clog.Warningf("invalid exclusion pattern '%s': %s", line, err)
+		return rule, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// convertIgnorePattern converts a gitignore glob pattern into a regular expression
+func convertIgnorePattern(pattern string) string {
+	builder := &strings.Builder{}
+	for i := 0; i < len(pattern); i++ {
+		char := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			// leading "**/" or "/**/" in the middle: any number of directories
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern) && i > 0 && pattern[i-1] == '/':
+			// trailing "/**": everything inside
+			builder.WriteString(".*")
+			i++
+		case char == '*':
+			builder.WriteString("[^/]*")
+		case char == '?':
+			builder.WriteString("[^/]")
+		case char == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				builder.WriteString(regexp.QuoteMeta(string(char)))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case char == '\\' && i+1 < len(pattern):
+			i++
+			builder.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	return builder.String()
+}
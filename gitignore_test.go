@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitIgnorePatterns(t *testing.T) {
+	testData := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"debug?.log", "debug1.log", false, true},
+		{"debug?.log", "debug10.log", false, false},
+		{"debug[0-9].log", "debug5.log", false, true},
+		{"debug[!0-9].log", "debug5.log", false, false},
+		{"debug[!0-9].log", "debugA.log", false, true},
+		{"/debug.log", "debug.log", false, true},
+		{"/debug.log", "logs/debug.log", false, false},
+		{"logs/debug.log", "logs/debug.log", false, true},
+		{"logs/debug.log", "build/logs/debug.log", false, false},
+		{"logs/", "logs", true, true},
+		{"logs/", "logs", false, false},
+		{"logs/", "build/logs", true, true},
+		{"**/logs", "build/logs", true, true},
+		{"**/logs", "logs", true, true},
+		{"logs/**", "logs/debug.log", false, true},
+		{"logs/**", "logs", true, false},
+		{"logs/**/debug.log", "logs/debug.log", false, true},
+		{"logs/**/debug.log", "logs/monday/pm/debug.log", false, true},
+		{"logs/*day/debug.log", "logs/monday/debug.log", false, true},
+		{"logs/*day/debug.log", "logs/monday/pm/debug.log", false, false},
+		{`\#file`, "#file", false, true},
+		{`\!important`, "!important", false, true},
+		{"trailing   ", "trailing", false, true},
+		{`space\ `, "space ", false, true},
+		{"*.c", "file.c.bak", false, false},
+	}
+	for _, testItem := range testData {
+		rule, ok := parseIgnoreLine(testItem.pattern)
+		require.True(t, ok, testItem.pattern)
+		ignore := &gitIgnore{files: []*ignoreFile{{base: "/base", rules: []ignoreRule{rule}}}}
+		assert.Equal(t, testItem.match, ignore.match("/base/"+testItem.path, testItem.isDir), "pattern %q on path %q", testItem.pattern, testItem.path)
+	}
+}
+
+func TestGitIgnoreSkipLines(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		_, ok := parseIgnoreLine(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestGitIgnoreNegation(t *testing.T) {
+	file := parseIgnoreLines("/base", []string{"*.log", "!important.log", "# comment", "dir/"})
+	ignore := &gitIgnore{files: []*ignoreFile{file}}
+	assert.True(t, ignore.match("/base/debug.log", false))
+	assert.False(t, ignore.match("/base/important.log", false))
+	assert.False(t, ignore.match("/base/sub/important.log", false))
+	assert.True(t, ignore.match("/base/sub/dir", true))
+	assert.False(t, ignore.match("/base/sub/dir", false))
+	assert.True(t, ignore.match("/base/.git", true))
+	// outside of the base directory
+	assert.False(t, ignore.match("/other/debug.log", false))
+	// but a name starting with dots is inside
+	assert.True(t, ignore.match("/base/..debug.log", false))
+	assert.True(t, ignore.match("/base/...config/dir", true))
+}
+
+func writeTestTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		fullName := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullName), 0700))
+		require.NoError(t, os.WriteFile(fullName, []byte(content), 0600))
+	}
+}
+
+func TestParserWithGitIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		".git/info/exclude":          "local.go\n",
+		".git/config.go":             testSource,
+		".gitignore":                 "/build/\n*_gen.go\n",
+		".copyrightignore":           "third_party\n",
+		"main.go":                    testSource,
+		"local.go":                   testSource,
+		"types_gen.go":               testSource,
+		"build/output.go":            testSource,
+		"third_party/lib.go":         testSource,
+		"pkg/build/keep.go":          testSource,
+		"pkg/.gitignore":             "*.go\n!keep.go\n!api.go\n",
+		"pkg/api.go":                 testSource,
+		"pkg/impl.go":                testSource,
+		"pkg/sub/.gitignore":         "!impl.go\n",
+		"pkg/sub/impl.go":            testSource,
+		"pkg/sub/types_gen.go":       testSource,
+		"other/.copyrightignore":     "/skip.go\n",
+		"other/skip.go":              testSource,
+		"other/sub/skip.go":          testSource,
+		"other/vendor/module/mod.go": testSource,
+	})
+
+	parser := NewParser([]string{".go"}, newExclusion("vendor")).UseGitIgnore()
+	fileQueue := parser.Directories([]string{root})
+	files := make([]string, 0, fileQueue.Len())
+	for e := fileQueue.Front(); e != nil; e = e.Next() {
+		relative, err := filepath.Rel(root, e.Value.(FileEntry).Name)
+		require.NoError(t, err)
+		files = append(files, filepath.ToSlash(relative))
+	}
+	sort.Strings(files)
+	assert.Equal(t, []string{
+		"main.go",
+		"other/sub/skip.go",
+		"pkg/api.go",
+		"pkg/build/keep.go",
+		"pkg/sub/impl.go",
+	}, files, strings.Join(files, ", "))
+}
+
+func TestParserInsideGitRepository(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		".git/HEAD":        "",
+		".gitignore":       "*.gen.go\n",
+		"src/.gitignore":   "skip.go\n",
+		"src/main.go":      testSource,
+		"src/skip.go":      testSource,
+		"src/main.gen.go":  testSource,
+		"src/pkg/file.go":  testSource,
+		"src/pkg/skip.go":  testSource,
+		"src/pkg/a.gen.go": testSource,
+	})
+
+	// the source directory is inside the repository: the .gitignore files above it are loaded
+	parser := NewParser([]string{".go"}, newExclusion()).UseGitIgnore()
+	fileQueue := parser.Directories([]string{filepath.Join(root, "src", "pkg")})
+	require.Equal(t, 1, fileQueue.Len())
+	assert.Equal(t, filepath.Join(root, "src", "pkg", "file.go"), fileQueue.Front().Value.(FileEntry).Name)
+}
@@ -0,0 +1,68 @@
+package main
+
+// extensionLanguages is the language of the files for each extension, available as {{.Language}} in the templates
+var extensionLanguages = map[string]string{
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".scss":  "SCSS",
+	".less":  "Less",
+	".go":    "Go",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".scala": "Scala",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".php":   "PHP",
+	".rs":    "Rust",
+	".swift": "Swift",
+	".dart":  "Dart",
+	".proto": "Protocol Buffers",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".pl":    "Perl",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".zsh":   "Shell",
+	".ps1":   "PowerShell",
+	".r":     "R",
+	".yml":   "YAML",
+	".yaml":  "YAML",
+	".toml":  "TOML",
+	".tf":    "Terraform",
+	".mk":    "Makefile",
+	".sql":   "SQL",
+	".lua":   "Lua",
+	".ada":   "Ada",
+	".el":    "Emacs Lisp",
+	".lisp":  "Lisp",
+	".clj":   "Clojure",
+	".ini":   "INI",
+	".asm":   "Assembly",
+	".html":  "HTML",
+	".htm":   "HTML",
+	".xml":   "XML",
+	".xaml":  "XAML",
+	".svg":   "SVG",
+	".vue":   "Vue",
+	".md":    "Markdown",
+	".hs":    "Haskell",
+	".elm":   "Elm",
+	".ml":    "OCaml",
+	".mli":   "OCaml",
+	".fs":    "F#",
+	".pas":   "Pascal",
+	".bat":   "Batch",
+	".cmd":   "Batch",
+}
+
+// getLanguage returns the language of the file extension (in lowercase), or an empty string when it's unknown
+func getLanguage(extension string) string {
+	return extensionLanguages[extension]
+}
@@ -4,7 +4,10 @@
 	"container/list"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/creativeprojects/clog"
@@ -16,13 +19,7 @@
 	maxFileSize = 2 * 1024 * 1024
 )
 
-type resultData struct {
-	fileName string
-	err      error
-}
-
 var (
-	results                []*list.List
 	detectGenericCopyright *regexp.Regexp
 	autoGenerated          *regexp.Regexp
 	randomGenerator        *rand.Rand
@@ -30,47 +27,143 @@ type resultData struct {
 )
 
 func init() {
-	results = make([]*list.List, fileStatusError+1)
-	for i := 0; i <= int(fileStatusError); i++ {
-		results[i] = &list.List{}
-	}
 	randomGenerator = rand.New(rand.NewSource(int64(time.Now().Nanosecond())))
 	detectGenericCopyright = regexp.MustCompile(`[\s/*-=]+Copyright[ \t]+`)
 	autoGenerated = regexp.MustCompile(`\<auto-generated\>`)
 }
 
 func main() {
+	os.Exit(run())
+}
 
+// run the command and returns the exit code
+func run() int {
 	flag.Parse()
 	if flags.help {
-		fmt.Print("\nUsage of copyright-notice:\n\n")
-		flag.PrintDefaults()
-		return
+		displayUsage()
+		return 0
 	}
 	close := setupLogger(flags)
 	defer close()
 
+	err := loadEnvironment()
+	if err != nil {
+		clog.Error(err)
+		return exitCodeFailure
+	}
+
+	command := commandApply
+	fileNames := flag.Args()
+	if len(fileNames) > 0 {
+		if _, found := commands[fileNames[0]]; found {
+			command = fileNames[0]
+			fileNames = fileNames[1:]
+		} else if _, err := os.Stat(fileNames[0]); err != nil {
+			// not a file either
+			clog.Errorf("unknown command '%s'", fileNames[0])
+			return exitCodeFailure
+		}
+	}
+	if command == commandTemplates {
+		// no configuration needed
+		return runTemplates(fileNames, os.Stdout)
+	}
+	if command == commandValidateConfig {
+		return runValidateConfig(flags.configFile)
+	}
+	if flags.filesFrom != "" {
+		list, err := readFileList(flags.filesFrom)
+		if err != nil {
+			clog.Errorf("cannot read the list of files: %s", err)
+			return exitCodeFailure
+		}
+		fileNames = append(fileNames, list...)
+	}
+	var selectedFiles fileSet
+	if len(fileNames) > 0 || flags.filesFrom != "" {
+		if flags.staged || flags.changedSince != "" {
+			clog.Error("a list of files cannot be used with --staged or --changed-since")
+			return exitCodeFailure
+		}
+		selectedFiles = newFileSet(fileNames)
+	}
+	if command == commandCheck {
+		// check never writes anything
+		flags.dryRun = true
+	}
+	if flags.staged && flags.changedSince != "" {
+		clog.Error("--staged and --changed-since cannot be used together")
+		return exitCodeFailure
+	}
+	if flags.staged && !flags.dryRun {
+		// the files on disk can be different from their version in the index
+		clog.Error("--staged can only be used with the check command or with --dry-run")
+		return exitCodeFailure
+	}
+	var report *Report
+	if flags.reportFormat != "" {
+		if !isReportFormat(flags.reportFormat) {
+			clog.Errorf("unknown report format '%s'", flags.reportFormat)
+			return exitCodeFailure
+		}
+		report = NewReport()
+	}
+
+	override, err := flags.profile()
+	if err != nil {
+		clog.Error(err)
+		return exitCodeFailure
+	}
 	// load configuration
-	config, err := LoadFileConfig(flags.configFile)
+	var config Config
+	if _, statErr := os.Stat(flags.configFile); statErr != nil && os.IsNotExist(statErr) && flags.hasProfile() {
+		clog.Debugf("no configuration file '%s': using the profile from the command line", flags.configFile)
+		config = flagsConfig()
+	} else {
+		config, err = LoadFileConfig(flags.configFile)
+		if err != nil {
+			clog.Errorf("cannot open configuration file: %s", err)
+			return exitCodeFailure
+		}
+	}
+	exitCode := 0
+	diff := &strings.Builder{}
+
+	workers := config.Workers
+	if flags.jobs > 0 {
+		workers = flags.jobs
+	}
+
+	profileNames, err := selectProfiles(config.ProfileNames(), flags.profiles)
 	if err != nil {
-		clog.Errorf("cannot open configuration file: %s", err)
+		clog.Error(err)
+		return exitCodeFailure
 	}
+	if flags.hasProfile() {
+		overrideProfiles(&config, profileNames, override)
+	}
+	// files already processed by a profile
+	claims := make(map[string]string)
 
-	for name, profile := range config.Profiles {
+	for _, name := range profileNames {
+		profile := config.Profiles[name]
 		// log prefix should be displayed only if we have more than one profile
-		if len(config.Profiles) > 1 {
+		if len(profileNames) > 1 {
 			clog.SetPrefix(name + ":  ")
 		}
 		if profile.Source == nil || len(*profile.Source) == 0 {
 			clog.Warning("no source folder defined, skipping profile")
+			exitCode |= exitCodeFailure
 			continue
 		}
 		if profile.Extensions == nil || len(*profile.Extensions) == 0 {
 			clog.Warning("no file extension defined, skipping profile")
+			exitCode |= exitCodeFailure
 			continue
 		}
 		if profile.Copyright == "" {
 			clog.Warning("no copyright file defined, skipping profile")
+			exitCode |= exitCodeFailure
 			continue
 		}
 		clog.Infof("searching for source files %s in folder %s", *profile.Extensions, *profile.Source)
@@ -81,6 +174,7 @@ func main() {
 			excludeList, err = readLines(profile.ExcludeFrom)
 			if err != nil {
 				clog.Warningf("error while reading exclusion file: %s, skipping profile", err)
+				exitCode |= exitCodeFailure
 				continue
 			}
 		}
@@ -92,98 +186,280 @@ func main() {
 
 		// Parse the source directory for files
 		parser := NewParser(*profile.Extensions, exclusions)
-		fileQueue := parser.Directories(*profile.Source)
+		if isTrue(profile.ExcludeFromGitIgnore) {
+			parser.UseGitIgnore()
+		}
+		var fileQueue *list.List
+		switch {
+		case selectedFiles != nil:
+			fileQueue = parser.Files(*profile.Source, fileNames)
+			for e := fileQueue.Front(); e != nil; e = e.Next() {
+				selectedFiles.selected(e.Value.(FileEntry).Name)
+			}
+		case flags.staged:
+			fileQueue = parser.StagedFiles(*profile.Source)
+		case flags.changedSince != "":
+			fileQueue = parser.ChangedFiles(*profile.Source, flags.changedSince)
+		default:
+			fileQueue = parser.Directories(*profile.Source)
+		}
 		if fileQueue.Len() == 0 {
+			if selectedFiles != nil {
+				// the list of files is probably meant for another profile
+				clog.Debug("no matching file found")
+				continue
+			}
 			clog.Warning("no matching file found")
 			continue
 		}
 
-		// Load the copyright notice template
-		copyrightTemplate, err := ParseCopyrightTemplateFromFile(profile.Copyright)
+		notice, err := loadNotice(name, profile)
 		if err != nil {
-			clog.Errorf("cannot load copyright template '%s': %s", profile.Copyright, err)
+			clog.Error(err)
+			exitCode |= exitCodeFailure
 			continue
 		}
-		copyrightNotice, err := copyrightTemplate.GetCopyrightNotice(&CopyrightData{Year: time.Now().Year()})
+		notice.remove = command == commandRemove
+		err = notice.loadDirectoryNotices(name, profile, fileQueue, parser.Settings)
 		if err != nil {
-			clog.Errorf("cannot load copyright template: %s", err)
+			clog.Error(err)
+			exitCode |= exitCodeFailure
 			continue
 		}
-
-		var ownPattern *regexp.Regexp
-		if profile.DetectOwn != "" {
-			ownPattern, err = regexp.Compile(profile.DetectOwn)
-			if err != nil {
-				clog.Errorf("cannot compile 'detect-own' regexp: %s", err)
-				continue
-			}
-		} else {
-			ownPattern, err = copyrightTemplate.GetRegexp()
-			if err != nil {
-				clog.Errorf("cannot transform copyright header into a regexp: %s", err)
-				continue
-			}
+		// the files are only claimed by a profile able to process them
+		claimFiles(fileQueue, name, claims)
+		if fileQueue.Len() == 0 {
+			clog.Debug("all the files were already processed by another profile")
+			continue
 		}
 
-		var genericPattern *regexp.Regexp
-		if profile.DetectOthers != "" {
-			genericPattern, err = regexp.Compile(profile.DetectOthers)
+		if isTrue(profile.CommitChanges) && !flags.dryRun {
+			// refuse before modifying anything
+			err = checkStagedChanges(fileQueue)
 			if err != nil {
-				clog.Errorf("cannot compile 'detect-others' regexp: %s", err)
+				clog.Error(err)
+				exitCode |= exitCodeFailure
 				continue
 			}
-		} else {
-			genericPattern = detectGenericCopyright
 		}
 
-		notice := NewNotice(genericPattern, ownPattern, false, false)
-
 		// Merge all files with the copyright notice
 		clog.Infof("analyzing %d source files", fileQueue.Len())
-		notice.checkForCopyrightNotices(fileQueue, copyrightNotice)
-		// fmt.Println("")
+		results := notice.checkForCopyrightNotices(fileQueue, workers)
 
+		if command == commandCheck {
+			displayCheckResults(results)
+			exitCode |= checkExitCode(results)
+		}
+		if report != nil {
+			report.Add(name, results)
+		}
+		if flags.diff {
+			diff.WriteString(results.Diff())
+		}
 		// Display results in debug mode
 		if flags.verbose {
-			displayDetailedResults()
+			displayDetailedResults(results)
 		} else {
-			displaySummaryResults()
+			displaySummaryResults(results)
+		}
+		if isTrue(profile.CommitChanges) && !flags.dryRun {
+			commits, err := commitChanges(name, profile, results)
+			for _, commit := range commits {
+				clog.Infof("committed %d %s in repository %s: %s", commit.Files, simplePlural("file", commit.Files), commit.Repository, commit.Hash)
+			}
+			if err != nil {
+				clog.Errorf("cannot commit changes: %s", err)
+				exitCode |= exitCodeFailure
+			}
 		}
 		clog.SetPrefix("")
 	}
-	if flags.dryRun {
+	for _, fileName := range selectedFiles.notSelected() {
+		clog.Warningf("file '%s' doesn't match any profile", fileName)
+	}
+	if flags.dryRun && command != commandCheck {
 		clog.Info("dry-run: nothing was changed")
 	}
+	if flags.diff {
+		err = writeDiff(diff.String(), flags.diffFile)
+		if err != nil {
+			clog.Errorf("cannot write diff: %s", err)
+			exitCode |= exitCodeFailure
+		}
+	}
+	if report != nil {
+		err = report.WriteFile(flags.reportFormat, flags.reportFile)
+		if err != nil {
+			clog.Errorf("cannot write report: %s", err)
+			return exitCode | exitCodeFailure
+		}
+	}
+	return exitCode
+}
+
+// loadNotice prepares the copyright notice from the profile template, and the patterns used to detect existing notices
+func loadNotice(name string, profile ConfigProfile) (Notice, error) {
+	year := ConfigLeaveYear
+	if profile.Year != nil {
+		year = *profile.Year
+	}
+	yearRange := ConfigExtendYearRange
+	if profile.YearRange != nil {
+		yearRange = *profile.YearRange
+	}
+	yearSource := ConfigYearFromClock
+	if profile.YearSource != nil {
+		yearSource = *profile.YearSource
+	}
+	bom := ConfigForgetBOM
+	if profile.BOM != nil {
+		bom = *profile.BOM
+	}
+
+	// Load the copyright notice template
+	copyrightTemplate, err := loadCopyrightTemplate(profile.Copyright)
+	if err != nil {
+		return Notice{}, fmt.Errorf("cannot load copyright template '%s': %w", profile.Copyright, err)
+	}
+	copyrightTemplate = copyrightTemplate.WithVariables(name, profile.Variables)
+	// a missing variable would only be found when adding a notice
+	sample := newFileData("source", name, profile.Variables)
+	sample.Year = time.Now().Year()
+	_, err = copyrightTemplate.GetCopyrightNotice(&sample)
+	if err != nil {
+		return Notice{}, fmt.Errorf("cannot use copyright template '%s': %w", profile.Copyright, err)
+	}
+
+	// Load the outdated templates to replace
+	replacedTemplates := make([]*CopyrightTemplate, 0)
+	if profile.Replaces != nil {
+		for _, filename := range *profile.Replaces {
+			replacedTemplate, err := loadCopyrightTemplate(filename)
+			if err != nil {
+				return Notice{}, fmt.Errorf("cannot load replaced copyright template '%s': %w", filename, err)
+			}
+			replacedTemplates = append(replacedTemplates, replacedTemplate.WithVariables(name, profile.Variables))
+		}
+	}
+
+	var detectOwn *regexp.Regexp
+	if profile.DetectOwn != "" {
+		detectOwn, err = regexp.Compile(profile.DetectOwn)
+		if err != nil {
+			return Notice{}, fmt.Errorf("cannot compile 'detect-own' regexp: %w", err)
+		}
+	}
+
+	genericPattern := detectGenericCopyright
+	if profile.DetectOthers != "" {
+		genericPattern, err = regexp.Compile(profile.DetectOthers)
+		if err != nil {
+			return Notice{}, fmt.Errorf("cannot compile 'detect-others' regexp: %w", err)
+		}
+	}
+
+	notice := NewNotice(genericPattern, year, yearRange, yearSource, bom)
+	notice.profile = name
+	notice.variables = profile.Variables
+	if profile.SPDX != "" {
+		spdx, unknown, err := parseSPDXExpression(profile.SPDX)
+		if err != nil {
+			return Notice{}, fmt.Errorf("invalid 'spdx' expression: %w", err)
+		}
+		if len(unknown) > 0 {
+			return Notice{}, fmt.Errorf("unknown SPDX identifier in 'spdx': %s", strings.Join(unknown, ", "))
+		}
+		notice.spdx = spdx
+		// the notice added to the files would never have the expected identifier
+		sample.SPDX = spdx
+		content, err := copyrightTemplate.GetCopyrightNotice(&sample)
+		if err != nil {
+			return Notice{}, fmt.Errorf("cannot use copyright template '%s': %w", profile.Copyright, err)
+		}
+		if !spdxLinePattern.Match(content) {
+			return Notice{}, fmt.Errorf("copyright template '%s' has no SPDX-License-Identifier line for 'spdx' (use {{.SPDX}})", profile.Copyright)
+		}
+	}
+	if profile.Source != nil {
+		notice.sources = *profile.Source
+	}
+
+	// the default header (with an empty extension) is only wrapped into a comment when a style is forced
+	extensions := []string{""}
+	if profile.Extensions != nil {
+		extensions = append(extensions, *profile.Extensions...)
+	}
+	for _, extension := range extensions {
+		comment, found := getCommentStyle(profile.CommentStyle, normalizeExtension(extension))
+		if !found {
+			if profile.CommentStyle != commentStyleAuto {
+				return Notice{}, fmt.Errorf("unknown comment style '%s'", profile.CommentStyle)
+			}
+			if extension != "" {
+				clog.Warningf("no comment style known for extension '%s': the copyright notice will be added as is", extension)
+			}
+		}
+		headerTemplate := copyrightTemplate.WithCommentStyle(comment)
+		ownPattern, err := loadHeader(headerTemplate, year)
+		if err != nil {
+			return Notice{}, err
+		}
+		if detectOwn != nil {
+			ownPattern = detectOwn
+		}
+		replaces := make([]*regexp.Regexp, len(replacedTemplates))
+		for index, replacedTemplate := range replacedTemplates {
+			if !replacedTemplate.IsComment() {
+				replacedTemplate = replacedTemplate.WithCommentStyle(comment)
+			}
+			replaces[index], err = replacedTemplate.GetRegexp()
+			if err != nil {
+				return Notice{}, fmt.Errorf("cannot transform replaced copyright header into a regexp: %w", err)
+			}
+		}
+		preamble, err := getPreambleRules(normalizeExtension(extension), profile.Preambles)
+		if err != nil {
+			return Notice{}, err
+		}
+		notice.setHeader(extension, header{
+			template:   headerTemplate,
+			replaces:   replaces,
+			ownPattern: ownPattern,
+			preamble:   preamble,
+		})
+	}
+	return notice, nil
 }
 
-func progress(fileName string, status fileStatus, err error) {
-	// Keep results for later use
-	results[status].PushBack(&resultData{fileName, err})
-	// Display live progress
-	// fmt.Print(status.Symbol())
+// normalizeExtension returns the last part of a profile extension ("d.ts" => ".ts") in lowercase
+func normalizeExtension(extension string) string {
+	return strings.ToLower(filepath.Ext(extension))
 }
 
-func displayDetailedResults() {
-	for _, status := range []fileStatus{
-		fileStatusNoCopyright,
-		fileStatusWithCopyright,
-		fileStatusCopyrightYearNeedsUpdated,
-		fileStatusAutoGenerated,
-		fileStatusOtherCopyright,
-		fileStatusTooBig,
-		fileStatusCannotOpen,
-		fileStatusError,
-	} {
-		displayResultList(results[status], status.String())
+// loadHeader returns the pattern to detect the copyright notice
+func loadHeader(copyrightTemplate *CopyrightTemplate, year ConfigYear) (*regexp.Regexp, error) {
+	var err error
+	var ownPattern *regexp.Regexp
+	if year == ConfigNoYear {
+		ownPattern, err = copyrightTemplate.GetRegexpWithoutYear()
+	} else {
+		ownPattern, err = copyrightTemplate.GetRegexp()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot transform copyright header into a regexp: %w", err)
 	}
+	return ownPattern, nil
 }
 
-func displayResultList(list *list.List, statusMessage string) {
-	if list == nil || list.Len() == 0 {
-		return
+func displayDetailedResults(results *Results) {
+	for _, status := range allFileStatus {
+		displayResultList(results.Get(status), status.String())
 	}
-	for e := list.Front(); e != nil; e = e.Next() {
-		status := e.Value.(*resultData)
+}
+
+func displayResultList(list []*resultData, statusMessage string) {
+	for _, status := range list {
 		details := fmt.Sprintf(", file: '%s'", status.fileName)
 		if status.err != nil {
 			details += fmt.Sprintf(", error: %s", status.err)
@@ -192,26 +468,17 @@ func displayResultList(list *list.List, statusMessage string) {
 	}
 }
 
-func displaySummaryResults() {
-	for _, status := range []fileStatus{
-		fileStatusNoCopyright,
-		fileStatusWithCopyright,
-		fileStatusCopyrightYearNeedsUpdated,
-		fileStatusAutoGenerated,
-		fileStatusOtherCopyright,
-		fileStatusTooBig,
-		fileStatusCannotOpen,
-		fileStatusError,
-	} {
-		displaySummary(results[status], status.String())
+func displaySummaryResults(results *Results) {
+	for _, status := range allFileStatus {
+		displaySummary(results.Len(status), status.String())
 	}
 }
 
-func displaySummary(list *list.List, message string) {
-	if list == nil || list.Len() == 0 {
+func displaySummary(count int, message string) {
+	if count == 0 {
 		return
 	}
-	clog.Infof(message+": %d %s", list.Len(), simplePlural("file", list.Len()))
+	clog.Infof(message+": %d %s", count, simplePlural("file", count))
 }
 
 func simplePlural(word string, count int) string {
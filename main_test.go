@@ -1,7 +1,19 @@
 package main
 
 import (
+	"container/list"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/creativeprojects/clog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -24,9 +36,498 @@ func init() {
 	// 	parseDir = gopath
 	// }
 	clog.SetDefaultLogger(clog.NewLogger(clog.NewDiscardHandler()))
+	progressOutput = nil
 	// parseDirectory(parseDir, exclusions, func(int) {}, func() {})
 }
 
+const (
+	testTemplate = "/*\n * Copyright (C) {{.Year}} TestCorp.\n */\n"
+	testSource   = "package main\n\nfunc main() {}\n"
+)
+
+// checkTestFile saves the content into a file, runs the profile on it and returns the new content of the file with the results
+func checkTestFile(t *testing.T, profile ConfigProfile, content string) (string, *Results) {
+	t.Helper()
+	return checkTestFileNamed(t, profile, "source.go", content)
+}
+
+func checkTestFileNamed(t *testing.T, profile ConfigProfile, name, content string) (string, *Results) {
+	t.Helper()
+	dir := t.TempDir()
+	if profile.Copyright == "" {
+		profile.Copyright = filepath.Join(dir, "copyright.txt")
+		require.NoError(t, os.WriteFile(profile.Copyright, []byte(testTemplate), 0600))
+	}
+	fileName := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(fileName, []byte(content), 0600))
+
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+
+	results := NewResults()
+	notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: int64(len(content))}, results)
+
+	result, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	return string(result), results
+}
+
+func yearPolicy(year ConfigYear) *ConfigYear {
+	return &year
+}
+
+func bomPolicy(bom ConfigBOM) *ConfigBOM {
+	return &bom
+}
+
+func TestUpdateYear(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	profile := ConfigProfile{Year: yearPolicy(ConfigUpdateYear)}
+
+	result, results := checkTestFile(t, profile, "/*\n * Copyright (C) 2015 TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) 2015-"+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusCopyrightYearNeedsUpdated))
+
+	result, results = checkTestFile(t, profile, "/*\n * Copyright (C) 2015, 2018-2020 TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) 2015, 2018-"+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusCopyrightYearNeedsUpdated))
+
+	replace := ConfigReplaceYearRange
+	result, results = checkTestFile(t, ConfigProfile{Year: yearPolicy(ConfigUpdateYear), YearRange: &replace}, "/*\n * Copyright (C) 2015-2020 TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusCopyrightYearNeedsUpdated))
+
+	result, results = checkTestFile(t, profile, "/*\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+
+	result, results = checkTestFile(t, profile, testSource)
+	assert.Equal(t, "/*\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+}
+
+func TestLeaveYear(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	profile := ConfigProfile{Year: yearPolicy(ConfigLeaveYear)}
+
+	result, results := checkTestFile(t, profile, "/*\n * Copyright (C) 2015 TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) 2015 TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+
+	result, results = checkTestFile(t, profile, testSource)
+	assert.Equal(t, "/*\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+}
+
+func TestNoYear(t *testing.T) {
+	profile := ConfigProfile{Year: yearPolicy(ConfigNoYear)}
+
+	result, results := checkTestFile(t, profile, testSource)
+	assert.Equal(t, "/*\n * Copyright (C) TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+
+	result, results = checkTestFile(t, profile, "/*\n * Copyright (C) TestCorp.\n */\n"+testSource)
+	assert.Equal(t, "/*\n * Copyright (C) TestCorp.\n */\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+	assert.Equal(t, 0, results.Len(fileStatusCannotFindCopyrightYear))
+
+	// the year is only removed when asked for: a default profile leaves it
+	result, _ = checkTestFile(t, ConfigProfile{}, testSource)
+	assert.Equal(t, "/*\n * Copyright (C) "+strconv.Itoa(time.Now().Year())+" TestCorp.\n */\n"+testSource, result)
+}
+
+func TestReplaceOutdatedHeader(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	dir := t.TempDir()
+	oldTemplate := filepath.Join(dir, "old.txt")
+	require.NoError(t, os.WriteFile(oldTemplate, []byte("/*\n * (c) {{.Year}} OldCorp\n */\n"), 0600))
+	oldTemplateNoYear := filepath.Join(dir, "old-no-year.txt")
+	require.NoError(t, os.WriteFile(oldTemplateNoYear, []byte("// OldCorp confidential\n"), 0600))
+	replaces := &StringSlice{oldTemplate, oldTemplateNoYear}
+
+	testData := []struct {
+		year     ConfigYear
+		source   string
+		expected string
+		status   fileStatus
+	}{
+		{ConfigLeaveYear, "/*\n * (c) 2015, 2018 OldCorp\n */\n\n" + testSource, "/*\n * Copyright (C) 2015, 2018 TestCorp.\n */\n\n" + testSource, fileStatusHeaderReplaced},
+		{ConfigUpdateYear, "/*\n * (c) 2015, 2018 OldCorp\n */\n\n" + testSource, "/*\n * Copyright (C) 2015, 2018-" + currentYear + " TestCorp.\n */\n\n" + testSource, fileStatusHeaderReplaced},
+		{ConfigNoYear, "/*\n * (c) 2015 OldCorp\n */\n" + testSource, "/*\n * Copyright (C) TestCorp.\n */\n" + testSource, fileStatusHeaderReplaced},
+		{ConfigLeaveYear, "// OldCorp confidential\n" + testSource, "/*\n * Copyright (C) " + currentYear + " TestCorp.\n */\n" + testSource, fileStatusHeaderReplaced},
+		// the preamble is kept above the header
+		{ConfigLeaveYear, "//go:build linux\n\n/*\n * (c) 2016 OldCorp\n */\n" + testSource, "//go:build linux\n\n/*\n * Copyright (C) 2016 TestCorp.\n */\n" + testSource, fileStatusHeaderReplaced},
+		// someone else's copyright is left alone
+		{ConfigLeaveYear, "/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, "/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, fileStatusOtherCopyright},
+		// the current header is not replaced
+		{ConfigLeaveYear, "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource, "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource, fileStatusWithCopyright},
+	}
+	for _, testItem := range testData {
+		profile := ConfigProfile{Year: yearPolicy(testItem.year), Replaces: replaces}
+		result, results := checkTestFile(t, profile, testItem.source)
+		assert.Equal(t, testItem.expected, result)
+		assert.Equal(t, 1, results.Len(testItem.status), testItem.source)
+	}
+
+	_, err := loadNotice("test", ConfigProfile{Copyright: oldTemplate, Replaces: &StringSlice{filepath.Join(dir, "not-found.txt")}})
+	assert.Error(t, err)
+}
+
+func TestReplaceCommentedHeader(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("Copyright (C) {{.Year}} TestCorp.\n"), 0600))
+	// the old header already is a comment: it is not wrapped into the comment style of the file
+	oldTemplate, err := os.ReadFile("short-copyright.txt")
+	require.NoError(t, err)
+	source := strings.ReplaceAll(string(oldTemplate), "{{.Year}}", "2015") + testSource
+
+	profile := ConfigProfile{
+		Copyright:    copyright,
+		CommentStyle: commentStyleAuto,
+		Extensions:   &StringSlice{".go"},
+		Year:         yearPolicy(ConfigLeaveYear),
+		Replaces:     &StringSlice{"short-copyright.txt"},
+	}
+	result, results := checkTestFile(t, profile, source)
+	assert.Equal(t, "/*\n * Copyright (C) 2015 TestCorp.\n */\n\n"+testSource, result)
+	assert.Equal(t, 1, results.Len(fileStatusHeaderReplaced))
+}
+
+func TestRemoveHeader(t *testing.T) {
+	bom := string(UTF8BOM)
+	header := "/*\n * Copyright (C) 2015 TestCorp.\n */\n"
+	testData := []struct {
+		source   string
+		expected string
+		status   fileStatus
+	}{
+		{header + testSource, testSource, fileStatusHeaderRemoved},
+		{header + "\n\n" + testSource, testSource, fileStatusHeaderRemoved},
+		{header + "\n  \tindented()\n", "  \tindented()\n", fileStatusHeaderRemoved},
+		{bom + header + testSource, bom + testSource, fileStatusHeaderRemoved},
+		{"#!/bin/sh\n" + header + "\necho\n", "#!/bin/sh\necho\n", fileStatusHeaderRemoved},
+		{header, "", fileStatusHeaderRemoved},
+		{testSource, testSource, fileStatusWithoutCopyright},
+		{"/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, "/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, fileStatusOtherCopyright},
+		{bom + "/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, bom + "/*\n * Copyright (C) 2015 OtherCorp.\n */\n" + testSource, fileStatusOtherCopyright},
+	}
+	for _, testItem := range testData {
+		dir := t.TempDir()
+		copyright := filepath.Join(dir, "copyright.txt")
+		require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+		fileName := filepath.Join(dir, "source.go")
+		require.NoError(t, os.WriteFile(fileName, []byte(testItem.source), 0600))
+
+		notice, err := loadNotice("test", ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigUpdateYear), BOM: bomPolicy(ConfigForgetBOM)})
+		require.NoError(t, err)
+		results := NewResults()
+		notice.removeCopyrightNoticeFromFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: int64(len(testItem.source))}, results)
+
+		result, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		assert.Equal(t, testItem.expected, string(result))
+		assert.Equal(t, 1, results.Len(testItem.status), testItem.source)
+	}
+}
+
+func TestRemoveCommand(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	source := "/*\n * Copyright (C) 2015 TestCorp.\n */\n\n" + testSource
+	writeTestTree(t, dir, map[string]string{"source.go": source})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "profiles:\n  test:\n    source: " + dir + "\n    extensions: go\n    year: leave\n    copyright: " + copyright + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	patch := filepath.Join(dir, "changes.patch")
+	exitCode := runCommand(t, "--config", configFile, "--dry-run", "--diff", "--diff-file", patch, commandRemove)
+	assert.Equal(t, 0, exitCode)
+	content, err := os.ReadFile(filepath.Join(dir, "source.go"))
+	require.NoError(t, err)
+	assert.Equal(t, source, string(content))
+	diff, err := os.ReadFile(patch)
+	require.NoError(t, err)
+	assert.Contains(t, string(diff), "-/*\n- * Copyright (C) 2015 TestCorp.\n- */\n-\n package main\n")
+
+	exitCode = runCommand(t, "--config", configFile, commandRemove)
+	assert.Equal(t, 0, exitCode)
+	content, err = os.ReadFile(filepath.Join(dir, "source.go"))
+	require.NoError(t, err)
+	assert.Equal(t, testSource, string(content))
+}
+
+func TestFileVariables(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("/*\n * {{.Path}}\n * Copyright (C) {{.Year}} {{.Vars.holder}}.\n */\n"), 0600))
+	writeTestTree(t, dir, map[string]string{
+		"src/pkg/new.go": testSource,
+		"src/moved.go":   "/*\n * pkg/old.go\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource,
+	})
+	profile := ConfigProfile{
+		Source:    &StringSlice{filepath.Join(dir, "src")},
+		Copyright: copyright,
+		Year:      yearPolicy(ConfigUpdateYear),
+		Variables: map[string]string{"holder": "TestCorp"},
+	}
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+
+	results := NewResults()
+	for _, name := range []string{"src/pkg/new.go", "src/moved.go"} {
+		fileName := filepath.Join(dir, name)
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, results)
+	}
+	assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+	assert.Equal(t, 1, results.Len(fileStatusCopyrightYearNeedsUpdated))
+
+	content, err := os.ReadFile(filepath.Join(dir, "src", "pkg", "new.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "/*\n * pkg/new.go\n * Copyright (C) "+currentYear+" TestCorp.\n */\n"+testSource, string(content))
+
+	// the header of a file moved somewhere else is still recognized
+	content, err = os.ReadFile(filepath.Join(dir, "src", "moved.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "/*\n * pkg/old.go\n * Copyright (C) 2015-"+currentYear+" TestCorp.\n */\n"+testSource, string(content))
+}
+
+func TestBOMPolicy(t *testing.T) {
+	currentYear := strconv.Itoa(time.Now().Year())
+	bom := string(UTF8BOM)
+	withHeader := "/*\n * Copyright (C) " + currentYear + " TestCorp.\n */\n" + testSource
+	withOldHeader := "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource
+	withUpdatedHeader := "/*\n * Copyright (C) 2015-" + currentYear + " TestCorp.\n */\n" + testSource
+
+	testData := []struct {
+		bom            ConfigBOM
+		source         string
+		expectedSource string
+	}{
+		{ConfigKeepBOM, bom + testSource, bom + withHeader},
+		{ConfigKeepBOM, testSource, withHeader},
+		{ConfigKeepBOM, bom + withOldHeader, bom + withUpdatedHeader},
+		{ConfigKeepBOM, withOldHeader, withUpdatedHeader},
+		{ConfigForgetBOM, bom + testSource, withHeader},
+		{ConfigForgetBOM, bom + withOldHeader, withUpdatedHeader},
+		{ConfigAddBOM, testSource, bom + withHeader},
+		{ConfigAddBOM, bom + testSource, bom + withHeader},
+		{ConfigAddBOM, withOldHeader, bom + withUpdatedHeader},
+		{ConfigAddBOM, bom + withOldHeader, bom + withUpdatedHeader},
+	}
+
+	for _, testItem := range testData {
+		profile := ConfigProfile{Year: yearPolicy(ConfigUpdateYear), BOM: bomPolicy(testItem.bom)}
+		result, _ := checkTestFile(t, profile, testItem.source)
+		assert.Equal(t, testItem.expectedSource, result)
+	}
+}
+
+func TestAutoCommentStyle(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("Copyright (C) {{.Year}} TestCorp.\n\nAll Rights Reserved\n"), 0600))
+	profile := ConfigProfile{
+		Copyright:    copyright,
+		CommentStyle: commentStyleAuto,
+		Extensions:   &StringSlice{".go", ".py", ".sql", ".html"},
+		Year:         yearPolicy(ConfigLeaveYear),
+	}
+
+	testData := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"source.go", testSource, "/*\n * Copyright (C) 2015 TestCorp.\n *\n * All Rights Reserved\n */\n\n" + testSource},
+		{"source.py", "print('hello')\n", "# Copyright (C) 2015 TestCorp.\n#\n# All Rights Reserved\n\n" + "print('hello')\n"},
+		{"source.sql", "SELECT 1;\n", "-- Copyright (C) 2015 TestCorp.\n--\n-- All Rights Reserved\n\n" + "SELECT 1;\n"},
+		{"source.html", "<p></p>\n", "<!--\n  Copyright (C) 2015 TestCorp.\n\n  All Rights Reserved\n-->\n\n" + "<p></p>\n"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.name, func(t *testing.T) {
+			// a file with an existing header is left alone
+			result, results := checkTestFileNamed(t, profile, testItem.name, testItem.expected)
+			assert.Equal(t, testItem.expected, result)
+			assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+
+			// also when the header is not followed by a blank line
+			existing := strings.TrimSuffix(testItem.expected, "\n"+testItem.source) + testItem.source
+			result, results = checkTestFileNamed(t, profile, testItem.name, existing)
+			assert.Equal(t, existing, result)
+			assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+
+			// a file without a header gets one wrapped into the right comment
+			result, results = checkTestFileNamed(t, profile, testItem.name, testItem.source)
+			expected := strings.ReplaceAll(testItem.expected, "2015", strconv.Itoa(time.Now().Year()))
+			assert.Equal(t, expected, result)
+			assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+		})
+	}
+}
+
+func TestCommentStyleWithWindowsEndOfLines(t *testing.T) {
+	testData := []struct {
+		name     string
+		template string
+		content  string
+	}{
+		{"source.py", "Copyright (C) {{.Year}} TestCorp.\n\nAll Rights Reserved\n", "# Copyright (C) 2015 TestCorp.\r\n#\r\n# All Rights Reserved\r\n\r\nprint('hello')\r\n"},
+		{"source.go", "Copyright (C) {{.Year}} TestCorp.\n\nAll Rights Reserved\n", "/*\r\n * Copyright (C) 2015 TestCorp.\r\n *\r\n * All Rights Reserved\r\n */\r\n\r\npackage main\r\n"},
+		// a template file saved with CRLF still finds the header of a file with LF
+		{"source.go", "Copyright (C) {{.Year}} TestCorp.\r\n\r\nAll Rights Reserved\r\n", "/*\n * Copyright (C) 2015 TestCorp.\n *\n * All Rights Reserved\n */\n\npackage main\n"},
+		{"source.sh", "# Copyright (C) {{.Year}} TestCorp.\r\n", "# Copyright (C) 2015 TestCorp.\necho hello\n"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.name, func(t *testing.T) {
+			dir := t.TempDir()
+			copyright := filepath.Join(dir, "copyright.txt")
+			require.NoError(t, os.WriteFile(copyright, []byte(testItem.template), 0600))
+			profile := ConfigProfile{
+				Copyright:    copyright,
+				CommentStyle: commentStyleAuto,
+				Extensions:   &StringSlice{".go", ".py", ".sh"},
+				Year:         yearPolicy(ConfigLeaveYear),
+			}
+			if strings.HasPrefix(testItem.template, "#") {
+				profile.CommentStyle = commentStyleNone
+			}
+			result, results := checkTestFileNamed(t, profile, testItem.name, testItem.content)
+			assert.Equal(t, testItem.content, result)
+			assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+		})
+	}
+}
+
+func TestUnknownCommentStyle(t *testing.T) {
+	_, err := loadNotice("test", ConfigProfile{Copyright: "short-copyright.txt", CommentStyle: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestKeepPreamble(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("Copyright TestCorp.\n"), 0600))
+	profile := ConfigProfile{
+		Copyright:    copyright,
+		CommentStyle: commentStyleAuto,
+		Extensions:   &StringSlice{".go", ".sh", ".xml", ".tpl"},
+		Preambles:    map[string]*StringSlice{".tpl": {`^\{\{/\*.*\*/\}\}$`}},
+	}
+
+	testData := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"build.go", "//go:build linux\n\npackage main\n", "//go:build linux\n\n/*\n * Copyright TestCorp.\n */\n\npackage main\n"},
+		{"script.sh", "#!/bin/sh\necho hello\n", "#!/bin/sh\n# Copyright TestCorp.\n\necho hello\n"},
+		{"data.xml", "<?xml version=\"1.0\"?>\n<data/>\n", "<?xml version=\"1.0\"?>\n<!--\n  Copyright TestCorp.\n-->\n\n<data/>\n"},
+		{"page.tpl", "{{/* page */}}\nhello\n", "{{/* page */}}\nCopyright TestCorp.\nhello\n"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.name, func(t *testing.T) {
+			result, results := checkTestFileNamed(t, profile, testItem.name, testItem.source)
+			assert.Equal(t, testItem.expected, result)
+			assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+		})
+	}
+}
+
+// createTestFiles creates a number of source files, every other one with a copyright notice
+func createTestFiles(t testing.TB, count int) *list.List {
+	dir := t.TempDir()
+	fileQueue := list.New()
+	for i := 0; i < count; i++ {
+		content := testSource
+		if i%2 == 0 {
+			content = "/*\n * Copyright (C) TestCorp.\n */\n" + testSource
+		}
+		fileName := filepath.Join(dir, fmt.Sprintf("source%d.go", i))
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0600))
+		fileQueue.PushBack(FileEntry{Name: fileName, Size: int64(len(content))})
+		if int64(len(content)) > maxSize {
+			maxSize = int64(len(content))
+		}
+	}
+	return fileQueue
+}
+
+func TestParallelAnalysis(t *testing.T) {
+	const count = 100
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	notice, err := loadNotice("test", ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigNoYear)})
+	require.NoError(t, err)
+
+	for _, workers := range []int{0, 1, 4, 16} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			fileQueue := createTestFiles(t, count)
+			results := notice.checkForCopyrightNotices(fileQueue, workers)
+			assert.Equal(t, count/2, results.Len(fileStatusWithCopyright))
+			assert.Equal(t, count/2, results.Len(fileStatusNoCopyright))
+
+			for e := fileQueue.Front(); e != nil; e = e.Next() {
+				content, err := os.ReadFile(e.Value.(FileEntry).Name)
+				require.NoError(t, err)
+				assert.Equal(t, "/*\n * Copyright (C) TestCorp.\n */\n"+testSource, string(content))
+			}
+		})
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	currentDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(currentDir)
+
+	require.NoError(t, os.WriteFile("copyright.txt", []byte(testTemplate), 0600))
+	sources := map[string]string{
+		"old.go":     "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource,
+		"without.go": testSource,
+		"script.go":  "//go:build linux\n\n" + testSource,
+	}
+	for name, content := range sources {
+		require.NoError(t, os.WriteFile(name, []byte(content), 0600))
+	}
+	config := "profiles:\n  test:\n    source: .\n    extensions: go\n    year: update\n    copyright: copyright.txt\n"
+	require.NoError(t, os.WriteFile("config.yaml", []byte(config), 0600))
+
+	exitCode := runCommand(t, "--config", "config.yaml", "--dry-run", "--diff", "--diff-file", "changes.patch")
+	assert.Equal(t, 0, exitCode)
+	for name, content := range sources {
+		result, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(result))
+	}
+
+	cmd := exec.Command("git", "apply", "changes.patch")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	currentYear := strconv.Itoa(time.Now().Year())
+	expected := map[string]string{
+		"old.go":     "/*\n * Copyright (C) 2015-" + currentYear + " TestCorp.\n */\n" + testSource,
+		"without.go": "/*\n * Copyright (C) " + currentYear + " TestCorp.\n */\n" + testSource,
+		"script.go":  "//go:build linux\n\n/*\n * Copyright (C) " + currentYear + " TestCorp.\n */\n" + testSource,
+	}
+	for name, content := range expected {
+		result, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(result))
+	}
+}
+
 // func TestMaxSize(t *testing.T) {
 // 	t.Log("Found", fileQueue.Len(), "files")
 // 	t.Log("Max file size", maxSize, "kb")
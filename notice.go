@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"container/list"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
+	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/creativeprojects/clog"
@@ -15,134 +18,433 @@
 	"github.com/vbauerster/mpb/v5/decor"
 )
 
+var (
+	// progressOutput is where the progress bar is displayed
+	progressOutput io.Writer = os.Stdout
+)
+
+// header is the copyright notice to add to a file, the pattern to detect it,
+// the patterns of the outdated notices to replace, and the rules to detect the lines which must stay above it
+type header struct {
+	template   *CopyrightTemplate // used to render the notice with the data of each file
+	ownPattern *regexp.Regexp
+	replaces   []*regexp.Regexp
+	preamble   []*regexp.Regexp
+}
+
 type Notice struct {
 	genericPattern *regexp.Regexp
-	ownPattern     *regexp.Regexp
-	updateYear     bool
-	keepUTF8BOM    bool
+	headers        map[string]header
+	year           ConfigYear
+	yearRange      ConfigYearRange
+	bom            ConfigBOM
+	years          *yearResolver
+	remove         bool // remove the copyright notices instead of adding them
+	profile        string
+	variables      map[string]string
+	sources        []string
+	spdx           string             // normalized SPDX license expression expected in the files
+	fileNotices    map[string]*Notice // notices of the files overridden by a directory configuration
 }
 
-func NewNotice(genericPattern, ownPattern *regexp.Regexp, updateYear, keepUTF8BOM bool) Notice {
+func NewNotice(genericPattern *regexp.Regexp, year ConfigYear, yearRange ConfigYearRange, yearSource ConfigYearSource, bom ConfigBOM) Notice {
 	return Notice{
 		genericPattern: genericPattern,
-		ownPattern:     ownPattern,
-		updateYear:     updateYear,
-		keepUTF8BOM:    keepUTF8BOM,
+		headers:        make(map[string]header),
+		year:           year,
+		yearRange:      yearRange,
+		bom:            bom,
+		years:          newYearResolver(yearSource),
+		fileNotices:    make(map[string]*Notice),
 	}
 }
 
-func (n Notice) checkForCopyrightNotices(fileQueue *list.List, copyrightNotice []byte) {
+// setHeader registers the copyright notice for the files ending with this extension.
+// The header registered with an empty extension is used for files not matching any other extension
+func (n Notice) setHeader(extension string, fileHeader header) {
+	n.headers[extension] = fileHeader
+}
+
+// getHeader returns the header registered with the longest extension matching the file name
+func (n Notice) getHeader(fileName string) (header, bool) {
+	found := ""
+	for extension := range n.headers {
+		if len(extension) > len(found) && strings.HasSuffix(fileName, extension) {
+			found = extension
+		}
+	}
+	fileHeader, ok := n.headers[found]
+	return fileHeader, ok
+}
+
+// getNotice returns the copyright notice to add to the file, rendered with the data of the file
+func (n Notice) getNotice(fileHeader header, fileName string) ([]byte, error) {
+	data := n.fileData(fileName)
+	if n.year == ConfigNoYear {
+		return fileHeader.template.GetCopyrightNoticeWithoutYear(&data)
+	}
+	return fileHeader.template.GetCopyrightNotice(&data)
+}
+
+// fileData returns the template data of the file. The years are only set when the notice has a year
+func (n Notice) fileData(fileName string) CopyrightData {
+	data := newFileData(n.relativePath(fileName), n.profile, n.variables)
+	data.SPDX = n.spdx
+	if n.year != ConfigNoYear {
+		years := n.years.years(fileName)
+		data.FirstYear = years.FirstYear
+		data.Year = years.Year
+	}
+	return data
+}
+
+// relativePath returns the path of the file relative to the source directory containing it.
+// It returns the file name when it's not inside any source directory
+func (n Notice) relativePath(fileName string) string {
+	absName := absolutePath(fileName)
+	for _, source := range n.sources {
+		relative, err := filepath.Rel(absolutePath(source), absName)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(relative)
+		}
+	}
+	return filepath.Base(fileName)
+}
+
+// checkForCopyrightNotices analyzes the files from the queue using a number of workers in parallel.
+// Each worker owns its own file buffer.
+func (n Notice) checkForCopyrightNotices(fileQueue *list.List, workers int) *Results {
 	start := time.Now()
-	progress := mpb.New()
+	if workers < 1 {
+		workers = 1
+	}
+	results := NewResults()
+	progress := mpb.New(mpb.WithOutput(progressOutput))
 	bar := progress.AddBar(int64(fileQueue.Len()),
 		mpb.PrependDecorators(decor.CountersNoUnit("files: %d / %d", decor.WC{})),
 		mpb.BarRemoveOnComplete())
 
-	file := NewFile(int(maxSize))
-
+	entries := make(chan FileEntry, workers)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			file := NewFile(int(maxSize))
+			for fileEntry := range entries {
+				notice := n.forFile(fileEntry.Name)
+				if notice.remove {
+					notice.removeCopyrightNoticeFromFile(file, fileEntry, results)
+				} else {
+					notice.checkForCopyrightNoticeInFile(file, fileEntry, results)
+				}
+				bar.Increment()
+			}
+		}()
+	}
 	for e := fileQueue.Front(); e != nil; e = e.Next() {
-		bar.Increment()
-		fileEntry := e.Value.(FileEntry)
-		n.checkForCopyrightNoticeInFile(file, fileEntry, copyrightNotice)
+		entries <- e.Value.(FileEntry)
 	}
+	close(entries)
+	wg.Wait()
 	progress.Wait()
 	clog.Infof("finished analyzing files in %s", time.Since(start))
+	return results
 }
 
-func (n Notice) checkForCopyrightNoticeInFile(file *File, fileEntry FileEntry, copyrightNotice []byte) {
-	var err error
-
-	err = file.Read(fileEntry.Name, fileEntry.Size)
-	if err != nil {
-		if e, ok := err.(*Error); ok {
-			switch e.Class() {
-			case FileErrorCannotOpen:
-				progress(fileEntry.Name, fileStatusCannotOpen, err)
-			case FileErrorTooBig:
-				progress(fileEntry.Name, fileStatusTooBig, err)
-			default:
-				progress(fileEntry.Name, fileStatusError, err)
-			}
-			return
-		}
-		progress(fileEntry.Name, fileStatusError, err)
-		return
-	}
-	if !file.IsReady() {
-		progress(fileEntry.Name, fileStatusError, errors.New("file reader hasn't finished reading"))
+func (n Notice) checkForCopyrightNoticeInFile(file *File, fileEntry FileEntry, results *Results) {
+	fileHeader, ok := n.readFile(file, fileEntry, results)
+	if !ok {
 		return
 	}
 	buffer := file.Bytes()
-	ignore := autoGenerated.FindIndex(buffer)
-	if ignore != nil {
-		progress(fileEntry.Name, fileStatusAutoGenerated, nil)
-		return
-	}
 	// Use the regexp to detect if the proper copyright header is present
-	found := n.ownPattern.FindIndex(buffer)
+	found := fileHeader.ownPattern.FindIndex(buffer)
 	if found != nil {
 		// Copyright header was found
-		if !n.updateYear {
-			// we're all good here
-			progress(fileEntry.Name, fileStatusWithCopyright, nil)
+		headerLines := newLineRange(buffer, found[0], found[1])
+		if n.year != ConfigUpdateYear {
+			// we're all good here: there's no year to check
+			n.addWithCopyright(buffer, found, fileEntry.Name, headerLines, results)
 			return
 		}
 		// now we need to check if the year is right
-		yearMatch := n.ownPattern.FindSubmatch(buffer)
-		// yearMatch: The first []byte is the whole match, then each one after are from the capturing parenthesis:
-		// so the next one will be the string before the year, then the year, then the rest of the line
-		if yearMatch == nil || len(yearMatch) <= 3 {
+		match := fileHeader.ownPattern.FindSubmatchIndex(buffer)
+		yearIndex := yearGroup(fileHeader.ownPattern)
+		if yearIndex < 0 || match[yearIndex*2] < 0 {
 			// Really, we should have found a year
-			progress(fileEntry.Name, fileStatusCannotFindCopyrightYear, fmt.Errorf("a year was not found in the copyright notice"))
+			results.AddWithHeader(fileEntry.Name, fileStatusCannotFindCopyrightYear, headerLines, fmt.Errorf("a year was not found in the copyright notice"))
 			return
 		}
-		if len(yearMatch) > 4 {
-			// So there's more than one copyright notice in the file?
-			// Convert [][]byte to string for the error message
-			displayYearRange := make([]string, len(yearMatch))
-			for index, element := range yearMatch {
-				displayYearRange[index] = "\"" + string(element) + "\""
-			}
-			progress(fileEntry.Name, fileStatusCannotFindCopyrightYear, fmt.Errorf("more than one year was found in the copyright notice: [ %v ]", strings.Join(displayYearRange, ", ")))
-			return
-		}
-		year, err := strconv.Atoi(string(yearMatch[2]))
+		yearStart, yearEnd := match[yearIndex*2], match[yearIndex*2+1]
+		yearRange, changed, err := updateYearExpression(string(buffer[yearStart:yearEnd]), n.years.years(fileEntry.Name).Year, n.yearRange)
 		if err != nil {
-			// not and integer?
-			progress(fileEntry.Name, fileStatusCannotFindCopyrightYear, fmt.Errorf("wrong format of year was found in the copyright notice"))
+			// not a year?
+			results.AddWithHeader(fileEntry.Name, fileStatusCannotFindCopyrightYear, headerLines, fmt.Errorf("wrong format of year was found in the copyright notice: %w", err))
 			return
 		}
-		currentYear := time.Now().Year()
-		if year < currentYear {
+		if changed {
 			// We need to update the existing copyright header
+			// only replace the year part of the first match
+			content := make([]byte, 0, len(buffer)+len(yearRange))
+			content = append(content, buffer[:yearStart]...)
+			content = append(content, yearRange...)
+			content = append(content, buffer[yearEnd:]...)
+			diff := ""
+			if flags.diff {
+				diff = unifiedDiff(fileEntry.Name, file.Raw(), file.PreviewReplace(content, n.bom))
+			}
 			if !flags.dryRun {
-				buffer = n.ownPattern.ReplaceAll(buffer, []byte("${1}"+strconv.Itoa(currentYear)+"${3}"))
-				err = ioutil.WriteFile(fileEntry.Name, buffer, 0666)
+				err = file.Replace(content, n.bom)
 				if err != nil {
-					progress(fileEntry.Name, fileStatusError, err)
+					results.Add(fileEntry.Name, fileStatusError, err)
 					return
 				}
 			}
-			progress(fileEntry.Name, fileStatusCopyrightYearNeedsUpdated, nil)
+			results.AddWithDiff(fileEntry.Name, fileStatusCopyrightYearNeedsUpdated, headerLines, diff)
 			return
 		}
-		progress(fileEntry.Name, fileStatusWithCopyright, nil)
+		n.addWithCopyright(buffer, found, fileEntry.Name, headerLines, results)
 	} else {
+		if n.replaceOutdatedHeader(file, fileHeader, fileEntry, results) {
+			return
+		}
 		// Check if there's some kind of copyright already
 		generic := n.genericPattern.FindIndex(buffer)
 		if generic != nil {
 			// someone's else file
-			progress(fileEntry.Name, fileStatusOtherCopyright, nil)
+			results.AddWithThirdParty(fileEntry.Name, fileStatusOtherCopyright, newLineRange(buffer, generic[0], generic[1]), classifyThirdPartyHeader(buffer, generic[0]))
 			return
 		}
 		// We need to add the new copyright header
+		copyrightNotice, err := n.getNotice(fileHeader, fileEntry.Name)
+		if err != nil {
+			results.Add(fileEntry.Name, fileStatusError, fmt.Errorf("cannot render copyright notice: %w", err))
+			return
+		}
+		position := preambleLength(buffer, fileHeader.preamble)
+		diff := ""
+		if flags.diff {
+			diff = unifiedDiff(fileEntry.Name, file.Raw(), file.PreviewHeader(copyrightNotice, position, n.bom))
+		}
 		if !flags.dryRun {
-			err = file.AddHeader(copyrightNotice, false) // TODO: Keep UTF8 BOM
+			err = file.AddHeader(copyrightNotice, position, n.bom)
 			if err != nil {
-				progress(fileEntry.Name, fileStatusError, err)
+				results.Add(fileEntry.Name, fileStatusError, err)
 				return
 			}
 		}
-		progress(fileEntry.Name, fileStatusNoCopyright, nil)
+		results.AddWithDiff(fileEntry.Name, fileStatusNoCopyright, nil, diff)
+	}
+}
+
+// addWithCopyright adds a file with our own copyright notice (found at these positions) to the results,
+// after checking its SPDX license identifier when the profile defines one
+func (n Notice) addWithCopyright(buffer []byte, found []int, fileName string, headerLines *lineRange, results *Results) {
+	if n.spdx == "" {
+		results.AddWithHeader(fileName, fileStatusWithCopyright, headerLines, nil)
+		return
+	}
+	status, err := n.checkSPDX(spdxArea(buffer, found[0], found[1]))
+	results.AddWithHeader(fileName, status, headerLines, err)
+}
+
+// spdxArea returns the part of the file where the SPDX license identifier of the notice is expected:
+// the notice itself and the comment block around it, but not the rest of the file
+func spdxArea(buffer []byte, start, end int) []byte {
+	// the expression can be at the end of the notice
+	if endOfLine := bytes.IndexByte(buffer[end:], '\n'); endOfLine > -1 {
+		end += endOfLine
+	} else {
+		end = len(buffer)
+	}
+	area := make([]byte, 0, end-start)
+	area = append(area, buffer[start:end]...)
+	for _, line := range headerBlock(buffer, start) {
+		area = append(area, '\n')
+		area = append(area, line...)
+	}
+	return area
+}
+
+// checkSPDX compares the SPDX license identifier of the header with the one of the profile
+func (n Notice) checkSPDX(header []byte) (fileStatus, error) {
+	expression, found := findSPDXExpression(header)
+	if !found {
+		return fileStatusMissingSPDX, fmt.Errorf("expected SPDX license identifier '%s'", n.spdx)
+	}
+	normalized, unknown, err := parseSPDXExpression(expression)
+	if err != nil {
+		return fileStatusUnknownSPDX, err
+	}
+	if len(unknown) > 0 {
+		return fileStatusUnknownSPDX, fmt.Errorf("unknown SPDX identifier: %s", strings.Join(unknown, ", "))
+	}
+	if !sameSPDXExpression(normalized, n.spdx) {
+		return fileStatusSPDXMismatch, fmt.Errorf("found SPDX license identifier '%s', expected '%s'", normalized, n.spdx)
+	}
+	return fileStatusWithCopyright, nil
+}
+
+// readFile loads the file into the buffer and returns its header. It returns false when the file should not be processed
+// (the status of the file is then added to the results)
+func (n Notice) readFile(file *File, fileEntry FileEntry, results *Results) (header, bool) {
+	var err error
+
+	fileHeader, ok := n.getHeader(fileEntry.Name)
+	if !ok {
+		results.Add(fileEntry.Name, fileStatusError, errors.New("no copyright notice defined for this type of file"))
+		return header{}, false
+	}
+
+	if fileEntry.Blob != "" {
+		err = file.ReadGitBlob(fileEntry.Name, fileEntry.Blob, fileEntry.Size)
+	} else {
+		err = file.Read(fileEntry.Name, fileEntry.Size)
+	}
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			switch e.Class() {
+			case FileErrorCannotOpen:
+				results.Add(fileEntry.Name, fileStatusCannotOpen, err)
+			case FileErrorTooBig:
+				results.Add(fileEntry.Name, fileStatusTooBig, err)
+			default:
+				results.Add(fileEntry.Name, fileStatusError, err)
+			}
+			return header{}, false
+		}
+		results.Add(fileEntry.Name, fileStatusError, err)
+		return header{}, false
+	}
+	if !file.IsReady() {
+		results.Add(fileEntry.Name, fileStatusError, errors.New("file reader hasn't finished reading"))
+		return header{}, false
+	}
+	ignore := autoGenerated.FindIndex(file.Bytes())
+	if ignore != nil {
+		results.Add(fileEntry.Name, fileStatusAutoGenerated, nil)
+		return header{}, false
+	}
+	return fileHeader, true
+}
+
+// removeCopyrightNoticeFromFile removes our own copyright notice from the file, with the blank lines following it
+func (n Notice) removeCopyrightNoticeFromFile(file *File, fileEntry FileEntry, results *Results) {
+	fileHeader, ok := n.readFile(file, fileEntry, results)
+	if !ok {
+		return
+	}
+	buffer := file.Bytes()
+	found := fileHeader.ownPattern.FindIndex(buffer)
+	if found == nil {
+		generic := n.genericPattern.FindIndex(buffer)
+		if generic != nil {
+			// someone's else file
+			results.AddWithThirdParty(fileEntry.Name, fileStatusOtherCopyright, newLineRange(buffer, generic[0], generic[1]), classifyThirdPartyHeader(buffer, generic[0]))
+			return
+		}
+		results.Add(fileEntry.Name, fileStatusWithoutCopyright, nil)
+		return
+	}
+	headerLines := newLineRange(buffer, found[0], found[1])
+	end := blankLinesEnd(buffer, found[0], found[1])
+	content := make([]byte, 0, len(buffer))
+	content = append(content, buffer[:found[0]]...)
+	content = append(content, buffer[end:]...)
+	diff := ""
+	// the BOM of the file is left untouched
+	if flags.diff {
+		diff = unifiedDiff(fileEntry.Name, file.Raw(), file.PreviewReplace(content, ConfigKeepBOM))
+	}
+	if !flags.dryRun {
+		err := file.Replace(content, ConfigKeepBOM)
+		if err != nil {
+			results.Add(fileEntry.Name, fileStatusError, err)
+			return
+		}
+	}
+	results.AddWithDiff(fileEntry.Name, fileStatusHeaderRemoved, headerLines, diff)
+}
+
+// replaceOutdatedHeader replaces an outdated copyright notice matching one of the replaced templates.
+// It returns false when no outdated notice was found
+func (n Notice) replaceOutdatedHeader(file *File, fileHeader header, fileEntry FileEntry, results *Results) bool {
+	buffer := file.Bytes()
+	for _, pattern := range fileHeader.replaces {
+		match := pattern.FindSubmatchIndex(buffer)
+		if match == nil {
+			continue
+		}
+		headerLines := newLineRange(buffer, match[0], match[1])
+		copyrightNotice, err := n.getReplacementNotice(fileHeader, fileEntry.Name, buffer, pattern, match)
+		if err != nil {
+			results.AddWithHeader(fileEntry.Name, fileStatusError, headerLines, fmt.Errorf("cannot render copyright notice: %w", err))
+			return true
+		}
+		end := noticeEnd(buffer, match[0], match[1])
+		content := make([]byte, 0, len(buffer)+len(copyrightNotice))
+		content = append(content, buffer[:match[0]]...)
+		content = append(content, copyrightNotice...)
+		content = append(content, buffer[end:]...)
+		diff := ""
+		if flags.diff {
+			diff = unifiedDiff(fileEntry.Name, file.Raw(), file.PreviewReplace(content, n.bom))
+		}
+		if !flags.dryRun {
+			err = file.Replace(content, n.bom)
+			if err != nil {
+				results.Add(fileEntry.Name, fileStatusError, err)
+				return true
+			}
+		}
+		results.AddWithDiff(fileEntry.Name, fileStatusHeaderReplaced, headerLines, diff)
+		return true
+	}
+	return false
+}
+
+// getReplacementNotice returns the copyright notice replacing an outdated one, keeping the years of the outdated notice
+func (n Notice) getReplacementNotice(fileHeader header, fileName string, buffer []byte, pattern *regexp.Regexp, match []int) ([]byte, error) {
+	yearIndex := yearGroup(pattern)
+	if n.year == ConfigNoYear || yearIndex < 0 || match[yearIndex*2] < 0 {
+		// the outdated notice has no year
+		return n.getNotice(fileHeader, fileName)
+	}
+	expression := string(buffer[match[yearIndex*2]:match[yearIndex*2+1]])
+	if n.year == ConfigUpdateYear {
+		updated, _, err := updateYearExpression(expression, n.years.years(fileName).Year, n.yearRange)
+		if err != nil {
+			return nil, err
+		}
+		expression = updated
+	}
+	data := n.fileData(fileName)
+	return fileHeader.template.GetCopyrightNoticeWithYears(&data, expression)
+}
+
+// noticeEnd returns the end of a notice matched by a pattern: the pattern also matches the blank lines after the notice,
+// but only the end of line of the last line of the notice belongs to it
+func noticeEnd(buffer []byte, start, end int) int {
+	trimmed := end
+	for trimmed > start && bytes.IndexByte([]byte(" \t\r\n"), buffer[trimmed-1]) > -1 {
+		trimmed--
+	}
+	if trimmed < end && buffer[trimmed] == '\r' {
+		trimmed++
+	}
+	if trimmed < end && buffer[trimmed] == '\n' {
+		trimmed++
+	}
+	return trimmed
+}
+
+// blankLinesEnd returns the end of a notice matched by a pattern, including the blank lines following it
+// but not the indentation of the next line
+func blankLinesEnd(buffer []byte, start, end int) int {
+	position := noticeEnd(buffer, start, end)
+	if lastLine := bytes.LastIndexByte(buffer[position:end], '\n'); lastLine > -1 {
+		return position + lastLine + 1
 	}
+	return position
 }
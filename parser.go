@@ -2,7 +2,9 @@
 
 import (
 	"container/list"
+	"errors"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -12,9 +14,11 @@
 )
 
 type Parser struct {
-	extensions []string
-	exclusions *exclusion
-	fileQueue  *list.List
+	extensions   []string
+	exclusions   *exclusion
+	useGitIgnore bool
+	fileQueue    *list.List
+	settings     directorySettingsCache
 }
 
 func NewParser(extensions []string, exclusions *exclusion) *Parser {
@@ -22,15 +26,28 @@ func NewParser(extensions []string, exclusions *exclusion) *Parser {
 		extensions: extensions,
 		exclusions: exclusions,
 		fileQueue:  list.New(),
+		settings:   make(directorySettingsCache),
 	}
 }
 
+// Settings returns the settings of the directory configuration files applying to the file
+func (p *Parser) Settings(fileName string) *directorySettings {
+	return p.settings.get(filepath.Dir(absolutePath(fileName)))
+}
+
+// UseGitIgnore also excludes the files matching the patterns from the .gitignore files,
+// the .git/info/exclude file of the repository and the .copyrightignore files
+func (p *Parser) UseGitIgnore() *Parser {
+	p.useGitIgnore = true
+	return p
+}
+
 func (p *Parser) Directories(directories []string) *list.List {
 	if directories == nil || len(directories) == 0 {
 		return p.fileQueue
 	}
 	total := int64(len(directories))
-	progress := mpb.New(nil)
+	progress := mpb.New(mpb.WithOutput(progressOutput))
 	spinner := progress.AddSpinner(total, mpb.SpinnerOnLeft,
 		mpb.PrependDecorators(decor.CountersNoUnit("directories and files analyzed: %d / %d", decor.WC{})),
 		mpb.BarRemoveOnComplete(),
@@ -39,7 +56,11 @@ func (p *Parser) Directories(directories []string) *list.List {
 		if source == "" {
 			continue
 		}
-		p.directory(source,
+		var ignore *gitIgnore
+		if p.useGitIgnore {
+			ignore = newGitIgnore(source)
+		}
+		p.directory(source, ignore,
 			func(more int) {
 				total += int64(more)
 				spinner.SetTotal(total, false)
@@ -53,7 +74,7 @@ func() {
 	return p.fileQueue
 }
 
-func (p *Parser) directory(directory string, addTotal func(int), addFile func()) {
+func (p *Parser) directory(directory string, ignore *gitIgnore, addTotal func(int), addFile func()) {
 	directory = filepath.Clean(directory)
 	files, err := ioutil.ReadDir(directory)
 	if err != nil {
@@ -62,6 +83,15 @@ func (p *Parser) directory(directory string, addTotal func(int), addFile func())
 	if files == nil || len(files) == 0 {
 		return
 	}
+	absDirectory := ""
+	if ignore != nil {
+		absDirectory, err = filepath.Abs(directory)
+		if err != nil {
+			clog.Errorf("cannot resolve directory: %s", err)
+			return
+		}
+		ignore = ignore.withDirectory(absDirectory)
+	}
 	addTotal(len(files))
 	for _, file := range files {
 		// Make sure we don't go into a infinite loop when running on unixes
@@ -74,19 +104,188 @@ func (p *Parser) directory(directory string, addTotal func(int), addFile func())
 			clog.Debugf("path excluded: '%s'", fullName)
 			continue
 		}
+		if ignore != nil && ignore.match(filepath.Join(absDirectory, file.Name()), file.IsDir()) {
+			clog.Debugf("path ignored: '%s'", fullName)
+			continue
+		}
+		if p.Settings(fullName).excluded(absolutePath(fullName)) {
+			clog.Debugf("path excluded by directory configuration: '%s'", fullName)
+			continue
+		}
 		addFile()
 		if file.IsDir() {
-			p.directory(fullName, addTotal, addFile)
-		} else if file.Size() > minFileSize && p.matchExtension(file.Name()) {
-			p.fileQueue.PushBack(FileEntry{fullName, file.Size()})
-			// update the max size of the files we're going to analyze,
-			// we keep the oversized file for reporting, but we won't build
-			// a buffer of that size
-			if file.Size() > maxSize && file.Size() <= maxFileSize {
-				maxSize = file.Size()
+			p.directory(fullName, ignore, addTotal, addFile)
+		} else {
+			p.addFile(FileEntry{Name: fullName, Size: file.Size()})
+		}
+	}
+}
+
+// addFile adds the file to the queue if it has one of the extensions
+func (p *Parser) addFile(entry FileEntry) {
+	if entry.Size <= minFileSize || !p.matchExtension(entry.Name) {
+		return
+	}
+	p.fileQueue.PushBack(entry)
+	// update the max size of the files we're going to analyze,
+	// we keep the oversized file for reporting, but we won't build
+	// a buffer of that size
+	if entry.Size > maxSize && entry.Size <= maxFileSize {
+		maxSize = entry.Size
+	}
+}
+
+// ChangedFiles only adds the files of the directories added, modified or renamed since the git reference
+func (p *Parser) ChangedFiles(directories []string, reference string) *list.List {
+	for _, source := range directories {
+		if source == "" {
+			continue
+		}
+		files, err := gitChangedFiles(source, reference, false)
+		if err != nil {
+			clog.Errorf("cannot list the files changed since '%s': %s", reference, err)
+			continue
+		}
+		p.fileList(source, files, func(fileName string) (FileEntry, error) {
+			info, err := os.Stat(fileName)
+			if err != nil {
+				return FileEntry{}, err
+			}
+			return FileEntry{Name: fileName, Size: info.Size()}, nil
+		})
+	}
+	return p.fileQueue
+}
+
+// StagedFiles only adds the files of the directories added, modified or renamed in the git index.
+// The version of the file in the index is analyzed instead of the file on disk
+func (p *Parser) StagedFiles(directories []string) *list.List {
+	for _, source := range directories {
+		if source == "" {
+			continue
+		}
+		files, err := gitChangedFiles(source, "", true)
+		if err != nil {
+			clog.Errorf("cannot list the staged files: %s", err)
+			continue
+		}
+		blobs, err := gitStagedBlobs(source)
+		if err != nil {
+			clog.Errorf("cannot list the staged files: %s", err)
+			continue
+		}
+		p.fileList(source, files, func(fileName string) (FileEntry, error) {
+			relative, _ := filepath.Rel(source, fileName)
+			blob, found := blobs[filepath.ToSlash(relative)]
+			if !found {
+				return FileEntry{}, errors.New("file not found in the index")
+			}
+			size, err := gitBlobSize(source, blob)
+			if err != nil {
+				return FileEntry{}, err
 			}
+			return FileEntry{Name: fileName, Size: size, Blob: blob}, nil
+		})
+	}
+	return p.fileQueue
+}
+
+// Files only adds the files from the list which are inside the directories
+func (p *Parser) Files(directories []string, fileNames []string) *list.List {
+	for _, source := range directories {
+		if source == "" {
+			continue
+		}
+		absSource, err := filepath.Abs(source)
+		if err != nil {
+			clog.Errorf("cannot resolve directory: %s", err)
+			continue
+		}
+		files := make([]string, 0, len(fileNames))
+		for _, fileName := range fileNames {
+			absName, err := filepath.Abs(fileName)
+			if err != nil {
+				continue
+			}
+			relative, err := filepath.Rel(absSource, absName)
+			if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+				// not inside the directory
+				continue
+			}
+			files = append(files, filepath.ToSlash(relative))
+		}
+		p.fileList(source, files, func(fileName string) (FileEntry, error) {
+			info, err := os.Stat(fileName)
+			if err != nil {
+				return FileEntry{}, err
+			}
+			if info.IsDir() {
+				return FileEntry{}, errors.New("not a file")
+			}
+			return FileEntry{Name: fileName, Size: info.Size()}, nil
+		})
+	}
+	return p.fileQueue
+}
+
+// fileList adds the files (relative to the source directory, with forward slashes) which are not excluded
+func (p *Parser) fileList(source string, files []string, getEntry func(fileName string) (FileEntry, error)) {
+	source = filepath.Clean(source)
+	var ignore *gitIgnore
+	absSource := ""
+	if p.useGitIgnore {
+		var err error
+		absSource, err = filepath.Abs(source)
+		if err != nil {
+			clog.Errorf("cannot resolve directory: %s", err)
+			return
+		}
+		ignore = newGitIgnore(absSource).withDirectory(absSource)
+	}
+	for _, file := range files {
+		if !p.matchExtension(file) || !p.included(source, absSource, file, ignore) {
+			continue
+		}
+		fullName := filepath.Join(source, filepath.FromSlash(file))
+		entry, err := getEntry(fullName)
+		if err != nil {
+			clog.Warningf("cannot read file '%s': %s", fullName, err)
+			continue
+		}
+		p.addFile(entry)
+	}
+}
+
+// included returns true when neither the file nor its parent directories below the source directory
+// are excluded or ignored, the same way they would be when parsing the source directory
+func (p *Parser) included(source, absSource, file string, ignore *gitIgnore) bool {
+	parts := strings.Split(file, "/")
+	fullName := source
+	absName := absSource
+	for index, part := range parts {
+		isDir := index < len(parts)-1
+		fullName = filepath.Join(fullName, part)
+		if p.exclusions.match(fullName) {
+			clog.Debugf("path excluded: '%s'", fullName)
+			return false
+		}
+		if p.Settings(fullName).excluded(absolutePath(fullName)) {
+			clog.Debugf("path excluded by directory configuration: '%s'", fullName)
+			return false
+		}
+		if ignore == nil {
+			continue
+		}
+		absName = filepath.Join(absName, part)
+		if ignore.match(absName, isDir) {
+			clog.Debugf("path ignored: '%s'", fullName)
+			return false
+		}
+		if isDir {
+			ignore = ignore.withDirectory(absName)
 		}
 	}
+	return true
 }
 
 func (p *Parser) matchExtension(fileName string) bool {
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// preambleAllFiles is the key of the rules applying to files of any extension
+	preambleAllFiles = "*"
+)
+
+var (
+	// defaultPreambleRules are the patterns of the lines which must stay at the top of a file, by file extension
+	defaultPreambleRules = map[string][]string{
+		preambleAllFiles: {`^#!`},
+		".go":            {`^//go:build `, `^// \+build `},
+		".php":           {`^<\?php`},
+		".py":            {`^#.*coding[:=]`},
+		".rb":            {`^#.*coding[:=]`, `^# frozen_string_literal:`},
+		".xml":           {`^<\?xml `, `(?i)^<!DOCTYPE `},
+		".xsd":           {`^<\?xml `},
+		".xsl":           {`^<\?xml `},
+		".xaml":          {`^<\?xml `},
+		".svg":           {`^<\?xml `, `(?i)^<!DOCTYPE `},
+		".plist":         {`^<\?xml `, `(?i)^<!DOCTYPE `},
+		".html":          {`^<\?xml `, `(?i)^<!DOCTYPE `},
+		".htm":           {`^<\?xml `, `(?i)^<!DOCTYPE `},
+		".xhtml":         {`^<\?xml `, `(?i)^<!DOCTYPE `},
+	}
+)
+
+// getPreambleRules returns the compiled preamble rules for the file extension:
+// the rules from the configuration are added to the default rules
+func getPreambleRules(extension string, configRules map[string]*StringSlice) ([]*regexp.Regexp, error) {
+	patterns := make([]string, 0)
+	patterns = append(patterns, defaultPreambleRules[preambleAllFiles]...)
+	if configRules[preambleAllFiles] != nil {
+		patterns = append(patterns, *configRules[preambleAllFiles]...)
+	}
+	if extension != "" {
+		patterns = append(patterns, defaultPreambleRules[extension]...)
+		if configRules[extension] != nil {
+			patterns = append(patterns, *configRules[extension]...)
+		}
+	}
+	rules := make([]*regexp.Regexp, len(patterns))
+	for index, pattern := range patterns {
+		rule, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile preamble regexp '%s': %w", pattern, err)
+		}
+		rules[index] = rule
+	}
+	return rules, nil
+}
+
+// preambleLength returns the number of bytes at the start of the content which must stay above the copyright notice:
+// the lines matching any of the rules, followed by any blank line
+func preambleLength(content []byte, rules []*regexp.Regexp) int {
+	if len(rules) == 0 {
+		return 0
+	}
+	position := 0
+	for position < len(content) {
+		line, next := nextLine(content, position)
+		if !matchAny(rules, line) {
+			break
+		}
+		position = next
+	}
+	if position == 0 {
+		return 0
+	}
+	// keep the blank lines after the preamble
+	for position < len(content) {
+		line, next := nextLine(content, position)
+		if len(bytes.TrimSpace(line)) > 0 {
+			break
+		}
+		position = next
+	}
+	return position
+}
+
+// nextLine returns the line starting at the position (without the end of line) and the position of the next line
+func nextLine(content []byte, position int) ([]byte, int) {
+	end := bytes.IndexByte(content[position:], '\n')
+	if end == -1 {
+		return content[position:], len(content)
+	}
+	return bytes.TrimRight(content[position:position+end], "\r"), position + end + 1
+}
+
+func matchAny(rules []*regexp.Regexp, line []byte) bool {
+	for _, rule := range rules {
+		if rule.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePreambleExtension adds a dot in front of the extension (except for the rule matching all files)
+func normalizePreambleExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if extension == preambleAllFiles || strings.HasPrefix(extension, ".") {
+		return extension
+	}
+	return "." + extension
+}
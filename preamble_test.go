@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreambleLength(t *testing.T) {
+	testData := []struct {
+		extension string
+		content   string
+		preamble  string
+	}{
+		{".sh", "echo hello\n", ""},
+		{".sh", "#!/bin/sh\necho hello\n", "#!/bin/sh\n"},
+		{".sh", "#!/bin/sh\n\n\necho hello\n", "#!/bin/sh\n\n\n"},
+		{".sh", "#!/bin/sh", "#!/bin/sh"},
+		{".py", "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\nimport os\n", "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n"},
+		{".py", "# some comment\nimport os\n", ""},
+		{".go", "//go:build linux\n// +build linux\n\npackage main\n", "//go:build linux\n// +build linux\n\n"},
+		{".go", "package main\n", ""},
+		{".xml", "<?xml version=\"1.0\"?>\r\n<!DOCTYPE note>\r\n<note/>\r\n", "<?xml version=\"1.0\"?>\r\n<!DOCTYPE note>\r\n"},
+		{".html", "<!doctype html>\n<html></html>\n", "<!doctype html>\n"},
+		{".php", "<?php\necho 'hello';\n", "<?php\n"},
+		{".js", "<?php\n", ""},
+		{"", "#!/bin/sh\n", "#!/bin/sh\n"},
+	}
+	for _, testItem := range testData {
+		rules, err := getPreambleRules(testItem.extension, nil)
+		require.NoError(t, err)
+		length := preambleLength([]byte(testItem.content), rules)
+		assert.Equal(t, testItem.preamble, testItem.content[:length], testItem.content)
+	}
+}
+
+func TestPreambleRulesFromConfig(t *testing.T) {
+	configRules := map[string]*StringSlice{
+		".tpl":           {`^\{\{/\*.*\*/\}\}$`},
+		preambleAllFiles: {`^# keep me$`},
+	}
+	rules, err := getPreambleRules(".tpl", configRules)
+	require.NoError(t, err)
+	content := "# keep me\n{{/* template */}}\nhello\n"
+	assert.Equal(t, "# keep me\n{{/* template */}}\n", content[:preambleLength([]byte(content), rules)])
+
+	rules, err = getPreambleRules(".txt", configRules)
+	require.NoError(t, err)
+	assert.Equal(t, "# keep me\n", content[:preambleLength([]byte(content), rules)])
+}
+
+func TestInvalidPreambleRule(t *testing.T) {
+	_, err := getPreambleRules(".txt", map[string]*StringSlice{".txt": {`^(`}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"path"
+
+	"github.com/creativeprojects/clog"
+)
+
+// selectProfiles returns the profiles matching any of the patterns (like "frontend-*"), in the same order.
+// All the profiles are selected when there's no pattern
+func selectProfiles(names, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return names, nil
+	}
+	matched := make(map[string]bool, len(names))
+	for _, pattern := range patterns {
+		found := false
+		for _, name := range names {
+			match, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid profile pattern '%s': %w", pattern, err)
+			}
+			if match {
+				matched[name] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no profile matching '%s'", pattern)
+		}
+	}
+	selected := make([]string, 0, len(matched))
+	for _, name := range names {
+		if matched[name] {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// claimFiles removes from the queue the files already claimed by another profile during this run,
+// so a file never receives two copyright notices. The other files are claimed by the profile
+func claimFiles(fileQueue *list.List, profile string, claims map[string]string) {
+	for e := fileQueue.Front(); e != nil; {
+		next := e.Next()
+		fileName := e.Value.(FileEntry).Name
+		absName := absolutePath(fileName)
+		if owner, found := claims[absName]; found {
+			clog.Warningf("file '%s' is already processed by profile '%s', skipping", fileName, owner)
+			fileQueue.Remove(e)
+		} else {
+			claims[absName] = profile
+		}
+		e = next
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectProfiles(t *testing.T) {
+	names := []string{"frontend-web", "backend", "frontend-mobile", "docs"}
+	testData := []struct {
+		patterns []string
+		selected []string
+	}{
+		{nil, names},
+		{[]string{"backend"}, []string{"backend"}},
+		{[]string{"frontend-*"}, []string{"frontend-web", "frontend-mobile"}},
+		{[]string{"docs", "frontend-w?b"}, []string{"frontend-web", "docs"}},
+		{[]string{"*", "docs"}, names},
+	}
+	for _, testItem := range testData {
+		selected, err := selectProfiles(names, testItem.patterns)
+		require.NoError(t, err)
+		assert.Equal(t, testItem.selected, selected, testItem.patterns)
+	}
+}
+
+func TestSelectProfilesErrors(t *testing.T) {
+	names := []string{"frontend", "backend"}
+	for _, pattern := range []string{"unknown", "front[", "backend-*"} {
+		_, err := selectProfiles(names, []string{"backend", pattern})
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestClaimFiles(t *testing.T) {
+	claims := make(map[string]string)
+	first := list.New()
+	first.PushBack(FileEntry{Name: "a.go"})
+	first.PushBack(FileEntry{Name: "b.go"})
+	claimFiles(first, "first", claims)
+	assert.Equal(t, 2, first.Len())
+
+	second := list.New()
+	second.PushBack(FileEntry{Name: "b.go"})
+	second.PushBack(FileEntry{Name: "c.go"})
+	second.PushBack(FileEntry{Name: "./a.go"})
+	claimFiles(second, "second", claims)
+	require.Equal(t, 1, second.Len())
+	assert.Equal(t, "c.go", second.Front().Value.(FileEntry).Name)
+	assert.Equal(t, "second", claims[absolutePath("c.go")])
+}
+
+func TestFileInTwoProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"src/main.go":   testSource,
+		"src/other.go":  testSource,
+		"copyright.txt": testTemplate,
+		"license.txt":   "// Licensed by TestCorp.\n",
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "defaults:\n  source: " + filepath.Join(dir, "src") + "\n  copyright: " + filepath.Join(dir, "copyright.txt") +
+		"\nprofiles:\n  first:\n    extensions: go\n  second:\n    extensions: [go, c]\n    copyright: " + filepath.Join(dir, "license.txt") +
+		"\n    detect-others: '^Licensed'\n  unrelated:\n    extensions: c\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", configFile, "--profile", "unknown"))
+
+	assert.Equal(t, 0, runCommand(t, "--config", configFile, "--profile", "*"))
+	for _, name := range []string{"main.go", "other.go"} {
+		content, err := os.ReadFile(filepath.Join(dir, "src", name))
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "Copyright"), name)
+		assert.NotContains(t, string(content), "Licensed", name)
+	}
+}
+
+func TestFailingProfileDoesNotClaimFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"src/main.go":   testSource,
+		"copyright.txt": testTemplate,
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "defaults:\n  source: " + filepath.Join(dir, "src") + "\n  extensions: go\nprofiles:\n  first:\n    copyright: " + filepath.Join(dir, "not-found.txt") +
+		"\n  second:\n    copyright: " + filepath.Join(dir, "copyright.txt") + "\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", configFile))
+	content, err := os.ReadFile(filepath.Join(dir, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "Copyright"))
+}
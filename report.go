@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	reportFormatJSON  = "json"
+	reportFormatJUnit = "junit"
+	reportFormatSARIF = "sarif"
+	// reportFormatInventory only lists the third-party copyright headers, in CSV
+	reportFormatInventory = "inventory"
+)
+
+func isReportFormat(format string) bool {
+	return format == reportFormatJSON || format == reportFormatJUnit || format == reportFormatSARIF || format == reportFormatInventory
+}
+
+// reportOutcome is how a file status is represented in a report
+type reportOutcome int
+
+const (
+	reportPass reportOutcome = iota
+	reportFailure
+	reportError
+	reportSkipped
+)
+
+// getReportOutcome returns how the status is represented in a report: the failures and errors are the ones making the check command fail
+func getReportOutcome(status fileStatus) reportOutcome {
+	switch checkExitCodes[status] {
+	case 0:
+		if status == fileStatusWithCopyright {
+			return reportPass
+		}
+		return reportSkipped
+	case exitCodeErrors:
+		return reportError
+	default:
+		return reportFailure
+	}
+}
+
+// Report collects the results of all the profiles
+type Report struct {
+	entries []reportEntry
+}
+
+type reportEntry struct {
+	File        string            `json:"file"`
+	Profile     string            `json:"profile"`
+	Status      string            `json:"status"`
+	Description string            `json:"description"`
+	Symbol      string            `json:"symbol"`
+	ErrorClass  string            `json:"error-class,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Header      *lineRange        `json:"header,omitempty"`
+	ThirdParty  *ThirdPartyHeader `json:"third-party,omitempty"`
+	status      fileStatus
+}
+
+// NewReport creates an empty report
+func NewReport() *Report {
+	return &Report{
+		entries: make([]reportEntry, 0),
+	}
+}
+
+// Add the results of a profile to the report
+func (r *Report) Add(profile string, results *Results) {
+	for _, status := range allFileStatus {
+		for _, result := range results.Get(status) {
+			entry := reportEntry{
+				File:        filepath.ToSlash(result.fileName),
+				Profile:     profile,
+				Status:      status.Name(),
+				Description: status.String(),
+				Symbol:      status.Symbol(),
+				Header:      result.header,
+				ThirdParty:  result.thirdParty,
+				status:      status,
+			}
+			if result.err != nil {
+				entry.Error = result.err.Error()
+				entry.ErrorClass = ErrorGeneric.String()
+				var fileError *Error
+				if errors.As(result.err, &fileError) {
+					entry.ErrorClass = fileError.Class().String()
+				}
+			}
+			r.entries = append(r.entries, entry)
+		}
+	}
+}
+
+// WriteFile saves the report in the format into the file (or to the console when the file name is empty or "-")
+func (r *Report) WriteFile(format, filename string) error {
+	if filename == "" || filename == "-" {
+		return r.Write(format, os.Stdout)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.Write(format, file)
+}
+
+// Write the report in the format (json, junit, sarif or inventory)
+func (r *Report) Write(format string, writer io.Writer) error {
+	switch format {
+	case reportFormatJSON:
+		return r.writeJSON(writer)
+	case reportFormatJUnit:
+		return r.writeJUnit(writer)
+	case reportFormatSARIF:
+		return r.writeSARIF(writer)
+	case reportFormatInventory:
+		return r.writeInventory(writer)
+	default:
+		return fmt.Errorf("unknown report format '%s'", format)
+	}
+}
+
+func (r *Report) writeJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Files []reportEntry `json:"files"`
+	}{
+		Files: r.entries,
+	})
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func (r *Report) writeJUnit(writer io.Writer) error {
+	suites := junitTestSuites{
+		Name: "copyright-notice",
+	}
+	suiteIndex := make(map[string]int)
+	for _, entry := range r.entries {
+		index, found := suiteIndex[entry.Profile]
+		if !found {
+			index = len(suites.Suites)
+			suiteIndex[entry.Profile] = index
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: entry.Profile})
+		}
+		suite := &suites.Suites[index]
+		testCase := junitTestCase{
+			ClassName: entry.Profile,
+			Name:      entry.File,
+		}
+		message := &junitMessage{
+			Message: entry.Description,
+			Type:    entry.Status,
+			Text:    entry.Error,
+		}
+		switch getReportOutcome(entry.status) {
+		case reportFailure:
+			testCase.Failure = message
+			suite.Failures++
+		case reportError:
+			testCase.Error = message
+			suite.Errors++
+		case reportSkipped:
+			testCase.Skipped = message
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+	}
+	_, err := io.WriteString(writer, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Kind       string            `json:"kind"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+func (r *Report) writeSARIF(writer io.Writer) error {
+	rules := make([]sarifRule, len(allFileStatus))
+	for index, status := range allFileStatus {
+		rules[index] = sarifRule{
+			ID:               status.Name(),
+			ShortDescription: sarifMessage{Text: status.String()},
+		}
+	}
+	results := make([]sarifResult, len(r.entries))
+	for index, entry := range r.entries {
+		result := sarifResult{
+			RuleID:  entry.Status,
+			Kind:    "fail",
+			Level:   "none",
+			Message: sarifMessage{Text: entry.Description},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: entry.File},
+				},
+			}},
+			Properties: map[string]string{
+				"profile": entry.Profile,
+				"symbol":  entry.Symbol,
+			},
+		}
+		switch getReportOutcome(entry.status) {
+		case reportPass:
+			result.Kind = "pass"
+		case reportSkipped:
+			result.Kind = "notApplicable"
+		case reportFailure:
+			result.Level = "warning"
+		case reportError:
+			result.Level = "error"
+		}
+		if entry.ThirdParty != nil {
+			result.Properties["license"] = entry.ThirdParty.License
+		}
+		if entry.Error != "" {
+			result.Message.Text += ": " + entry.Error
+			result.Properties["errorClass"] = entry.ErrorClass
+		}
+		if entry.Header != nil {
+			result.Locations[0].PhysicalLocation.Region = &sarifRegion{
+				StartLine: entry.Header.Start,
+				EndLine:   entry.Header.End,
+			}
+		}
+		results[index] = result
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           "copyright-notice",
+					InformationURI: "https://github.com/creativeprojects/copyright-notice",
+					Rules:          rules,
+				},
+			},
+			Results: results,
+		}},
+	})
+}
+
+// writeInventory writes the third-party copyright headers in CSV, sorted by license and file name
+func (r *Report) writeInventory(writer io.Writer) error {
+	entries := make([]reportEntry, 0)
+	for _, entry := range r.entries {
+		if entry.ThirdParty != nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].ThirdParty.License != entries[j].ThirdParty.License {
+			return entries[i].ThirdParty.License < entries[j].ThirdParty.License
+		}
+		return entries[i].File < entries[j].File
+	})
+	output := csv.NewWriter(writer)
+	err := output.Write([]string{"license", "holders", "years", "file", "profile", "start-line", "end-line"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		start, end := "", ""
+		if entry.Header != nil {
+			start, end = strconv.Itoa(entry.Header.Start), strconv.Itoa(entry.Header.End)
+		}
+		err = output.Write([]string{
+			entry.ThirdParty.License,
+			strings.Join(entry.ThirdParty.Holders, "; "),
+			strings.Join(entry.ThirdParty.Years, "; "),
+			entry.File,
+			entry.Profile,
+			start,
+			end,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	output.Flush()
+	return output.Error()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	results := NewResults()
+	results.AddWithHeader("src/with.go", fileStatusWithCopyright, &lineRange{1, 3}, nil)
+	results.Add("src/without.go", fileStatusNoCopyright, nil)
+	results.AddWithHeader("src/old.go", fileStatusCopyrightYearNeedsUpdated, &lineRange{2, 4}, nil)
+	results.Add("src/generated.go", fileStatusAutoGenerated, nil)
+	results.Add("src/missing.go", fileStatusCannotOpen, NewError(FileErrorCannotOpen, errors.New("file not found")))
+	results.Add("src/broken.go", fileStatusError, errors.New("disk full"))
+
+	report := NewReport()
+	report.Add("test", results)
+	return report
+}
+
+func TestJSONReport(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	err := testReport().Write(reportFormatJSON, buffer)
+	require.NoError(t, err)
+
+	decoded := struct {
+		Files []map[string]interface{} `json:"files"`
+	}{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+	require.Len(t, decoded.Files, 6)
+
+	files := make(map[string]map[string]interface{})
+	for _, file := range decoded.Files {
+		assert.Equal(t, "test", file["profile"])
+		files[file["file"].(string)] = file
+	}
+	assert.Equal(t, "with-copyright", files["src/with.go"]["status"])
+	assert.Equal(t, ".", files["src/with.go"]["symbol"])
+	assert.Equal(t, map[string]interface{}{"start": 1.0, "end": 3.0}, files["src/with.go"]["header"])
+	assert.Equal(t, "no-copyright", files["src/without.go"]["status"])
+	assert.NotContains(t, files["src/without.go"], "header")
+	assert.NotContains(t, files["src/without.go"], "error")
+	assert.Equal(t, "cannot open file", files["src/missing.go"]["error-class"])
+	assert.Equal(t, "error", files["src/broken.go"]["error-class"])
+	assert.Equal(t, "disk full", files["src/broken.go"]["error"])
+}
+
+func TestJUnitReport(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	err := testReport().Write(reportFormatJUnit, buffer)
+	require.NoError(t, err)
+
+	suites := junitTestSuites{}
+	require.NoError(t, xml.Unmarshal(buffer.Bytes(), &suites))
+	assert.Equal(t, 6, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	assert.Equal(t, 2, suites.Errors)
+	assert.Equal(t, 1, suites.Skipped)
+	require.Len(t, suites.Suites, 1)
+	assert.Equal(t, "test", suites.Suites[0].Name)
+	assert.Len(t, suites.Suites[0].Cases, 6)
+}
+
+func TestSARIFReport(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	err := testReport().Write(reportFormatSARIF, buffer)
+	require.NoError(t, err)
+
+	log := sarifLog{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(allFileStatus))
+	require.Len(t, log.Runs[0].Results, 6)
+
+	results := make(map[string]sarifResult)
+	for _, result := range log.Runs[0].Results {
+		results[result.Locations[0].PhysicalLocation.ArtifactLocation.URI] = result
+	}
+	assert.Equal(t, "pass", results["src/with.go"].Kind)
+	assert.Equal(t, "none", results["src/with.go"].Level)
+	assert.Equal(t, &sarifRegion{1, 3}, results["src/with.go"].Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, "warning", results["src/without.go"].Level)
+	assert.Equal(t, "warning", results["src/old.go"].Level)
+	assert.Equal(t, "notApplicable", results["src/generated.go"].Kind)
+	assert.Equal(t, "error", results["src/broken.go"].Level)
+	assert.Equal(t, "test", results["src/broken.go"].Properties["profile"])
+}
+
+func TestUnknownReportFormat(t *testing.T) {
+	assert.False(t, isReportFormat("html"))
+	assert.Error(t, testReport().Write("html", &bytes.Buffer{}))
+}
+
+func TestInventoryReport(t *testing.T) {
+	results := NewResults()
+	results.AddWithHeader("src/with.go", fileStatusWithCopyright, &lineRange{1, 3}, nil)
+	results.AddWithThirdParty("vendor/b.go", fileStatusOtherCopyright, &lineRange{2, 2}, &ThirdPartyHeader{License: "MIT", Holders: []string{"Jane Doe"}, Years: []string{"2018"}})
+	results.AddWithThirdParty("vendor/a.go", fileStatusOtherCopyright, &lineRange{1, 1}, &ThirdPartyHeader{License: "MIT", Holders: []string{"Foo", "Bar"}, Years: []string{"2015", "2016-2018"}})
+	results.AddWithThirdParty("vendor/c.go", fileStatusOtherCopyright, &lineRange{1, 1}, &ThirdPartyHeader{License: "Apache-2.0", Holders: []string{"Google LLC"}})
+	report := NewReport()
+	report.Add("test", results)
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, report.Write(reportFormatInventory, buffer))
+	assert.Equal(t, "license,holders,years,file,profile,start-line,end-line\n"+
+		"Apache-2.0,Google LLC,,vendor/c.go,test,1,1\n"+
+		"MIT,Foo; Bar,2015; 2016-2018,vendor/a.go,test,1,1\n"+
+		"MIT,Jane Doe,2018,vendor/b.go,test,2,2\n", buffer.String())
+
+	// the details are also in the JSON report
+	buffer.Reset()
+	require.NoError(t, report.Write(reportFormatJSON, buffer))
+	assert.Contains(t, buffer.String(), `"third-party": {`)
+	assert.Contains(t, buffer.String(), `"license": "Apache-2.0"`)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type resultData struct {
+	fileName   string
+	err        error
+	header     *lineRange        // lines of the copyright header found in the file
+	diff       string            // changes made (or which would be made in dry-run) to the file
+	thirdParty *ThirdPartyHeader // what was recognized in a copyright header which is not our own
+}
+
+// lineRange is a range of lines in a file (starting at line 1)
+type lineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// newLineRange returns the range of lines of the content between the start and end indexes.
+// Any blank space at the start or at the end of the range is ignored.
+func newLineRange(content []byte, start, end int) *lineRange {
+	for start < end && bytes.IndexByte([]byte(" \t\r\n"), content[start]) > -1 {
+		start++
+	}
+	startLine := bytes.Count(content[:start], []byte("\n")) + 1
+	block := bytes.TrimRight(content[start:end], " \t\r\n")
+	return &lineRange{
+		Start: startLine,
+		End:   startLine + bytes.Count(block, []byte("\n")),
+	}
+}
+
+// Results collects the status of each file analyzed. It is safe for concurrent use
+type Results struct {
+	mu     sync.Mutex
+	status [][]*resultData
+}
+
+// NewResults creates an empty list of results
+func NewResults() *Results {
+	return &Results{
+		status: make([][]*resultData, fileStatusError+1),
+	}
+}
+
+// Add the status of a file
+func (r *Results) Add(fileName string, status fileStatus, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status[status] = append(r.status[status], &resultData{fileName: fileName, err: err})
+}
+
+// AddWithHeader adds the status of a file where a copyright header was found
+func (r *Results) AddWithHeader(fileName string, status fileStatus, header *lineRange, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status[status] = append(r.status[status], &resultData{fileName: fileName, err: err, header: header})
+}
+
+// AddWithThirdParty adds the status of a file with a copyright header which is not our own
+func (r *Results) AddWithThirdParty(fileName string, status fileStatus, header *lineRange, thirdParty *ThirdPartyHeader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status[status] = append(r.status[status], &resultData{fileName: fileName, header: header, thirdParty: thirdParty})
+}
+
+// AddWithDiff adds the status of a file with the changes made to it
+func (r *Results) AddWithDiff(fileName string, status fileStatus, header *lineRange, diff string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status[status] = append(r.status[status], &resultData{fileName: fileName, header: header, diff: diff})
+}
+
+// Diff returns the changes of all the files, sorted by file name
+func (r *Results) Diff() string {
+	all := make([]*resultData, 0)
+	for _, status := range allFileStatus {
+		all = append(all, r.Get(status)...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].fileName < all[j].fileName
+	})
+	builder := &strings.Builder{}
+	for _, result := range all {
+		builder.WriteString(result.diff)
+	}
+	return builder.String()
+}
+
+// Get returns the results for this status, sorted by file name
+func (r *Results) Get(status fileStatus) []*resultData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]*resultData, len(r.status[status]))
+	copy(list, r.status[status])
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].fileName < list[j].fileName
+	})
+	return list
+}
+
+// Len returns the number of files with this status
+func (r *Results) Len(status fileStatus) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.status[status])
+}
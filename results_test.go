@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsSortedByFileName(t *testing.T) {
+	results := NewResults()
+	results.Add("c", fileStatusNoCopyright, nil)
+	results.Add("a", fileStatusNoCopyright, nil)
+	results.Add("b", fileStatusNoCopyright, nil)
+	results.Add("d", fileStatusError, nil)
+
+	list := results.Get(fileStatusNoCopyright)
+	assert.Len(t, list, 3)
+	assert.Equal(t, "a", list[0].fileName)
+	assert.Equal(t, "b", list[1].fileName)
+	assert.Equal(t, "c", list[2].fileName)
+	assert.Equal(t, 1, results.Len(fileStatusError))
+	assert.Equal(t, 0, results.Len(fileStatusWithCopyright))
+}
+
+func TestNewLineRange(t *testing.T) {
+	content := []byte("#!/bin/sh\n\n# Copyright\n# TestCorp\n\necho\n")
+	testData := []struct {
+		match    string
+		expected lineRange
+	}{
+		{"#!/bin/sh", lineRange{1, 1}},
+		{"\n\n# Copyright\n# TestCorp\n\n", lineRange{3, 4}},
+		{"# Copyright\n", lineRange{3, 3}},
+		{"echo\n", lineRange{6, 6}},
+	}
+	for _, testItem := range testData {
+		start := bytes.Index(content, []byte(testItem.match))
+		assert.Equal(t, testItem.expected, *newLineRange(content, start, start+len(testItem.match)), testItem.match)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	//go:embed spdx/licenses.txt
+	spdxLicenseList string
+	//go:embed spdx/exceptions.txt
+	spdxExceptionList string
+
+	// spdxLicenses and spdxExceptions are the known identifiers, by their lowercase version
+	spdxLicenses   = parseSPDXList(spdxLicenseList)
+	spdxExceptions = parseSPDXList(spdxExceptionList)
+
+	spdxLinePattern = regexp.MustCompile(`SPDX-License-Identifier:[ \t]*([^\r\n]*)`)
+)
+
+const (
+	spdxAnd  = "AND"
+	spdxOr   = "OR"
+	spdxWith = "WITH"
+)
+
+func parseSPDXList(list string) map[string]string {
+	identifiers := make(map[string]string)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		identifiers[strings.ToLower(line)] = line
+	}
+	return identifiers
+}
+
+// findSPDXExpression returns the expression of the first SPDX-License-Identifier line of the content.
+// The end of a comment on the same line is not part of the expression
+func findSPDXExpression(content []byte) (string, bool) {
+	match := spdxLinePattern.FindSubmatch(content)
+	if match == nil {
+		return "", false
+	}
+	expression := strings.TrimSpace(string(match[1]))
+	for _, end := range []string{"*/", "-->", "-}", "*)"} {
+		expression = strings.TrimSpace(strings.TrimSuffix(expression, end))
+	}
+	return expression, true
+}
+
+// spdxParser parses an SPDX license expression like "(MIT OR Apache-2.0) AND GPL-2.0-or-later WITH Classpath-exception-2.0"
+type spdxParser struct {
+	tokens   []string
+	position int
+	unknown  []string
+}
+
+// spdxTerm is a parsed SPDX expression: a license (with its exception), or the operands of an operator
+type spdxTerm struct {
+	text     string // normalized text, in the original order
+	license  string
+	operator string
+	operands []spdxTerm
+}
+
+// parseSPDXExpression returns the expression normalized (identifiers with their official case and single spaces),
+// and the list of identifiers which are not in the SPDX license list
+func parseSPDXExpression(expression string) (string, []string, error) {
+	term, unknown, err := parseSPDXTerm(expression)
+	if err != nil {
+		return "", nil, err
+	}
+	return term.text, unknown, nil
+}
+
+// sameSPDXExpression returns true when both valid expressions are the same, whatever the order of the operands of AND and OR
+func sameSPDXExpression(expression1, expression2 string) bool {
+	term1, _, err := parseSPDXTerm(expression1)
+	if err != nil {
+		return false
+	}
+	term2, _, err := parseSPDXTerm(expression2)
+	if err != nil {
+		return false
+	}
+	return term1.canonical() == term2.canonical()
+}
+
+func parseSPDXTerm(expression string) (spdxTerm, []string, error) {
+	parser := &spdxParser{
+		tokens:  tokenizeSPDXExpression(expression),
+		unknown: make([]string, 0),
+	}
+	if len(parser.tokens) == 0 {
+		return spdxTerm{}, nil, errors.New("empty SPDX expression")
+	}
+	term, err := parser.orExpression()
+	if err != nil {
+		return spdxTerm{}, nil, err
+	}
+	if parser.position < len(parser.tokens) {
+		return spdxTerm{}, nil, fmt.Errorf("unexpected %q in SPDX expression", parser.tokens[parser.position])
+	}
+	return term, parser.unknown, nil
+}
+
+// canonical returns the expression with the operands of AND and OR sorted, and without redundant parentheses
+func (t spdxTerm) canonical() string {
+	if t.operator == "" {
+		return t.license
+	}
+	operands := t.flatten(t.operator)
+	sort.Strings(operands)
+	return strings.Join(operands, " "+t.operator+" ")
+}
+
+// flatten returns the canonical operands of the operator: "(A OR B) OR C" has the same operands as "A OR B OR C"
+func (t spdxTerm) flatten(operator string) []string {
+	if t.operator == "" {
+		return []string{t.license}
+	}
+	if t.operator != operator {
+		return []string{"(" + t.canonical() + ")"}
+	}
+	operands := make([]string, 0, len(t.operands))
+	for _, operand := range t.operands {
+		operands = append(operands, operand.flatten(operator)...)
+	}
+	return operands
+}
+
+func tokenizeSPDXExpression(expression string) []string {
+	expression = strings.ReplaceAll(expression, "(", " ( ")
+	expression = strings.ReplaceAll(expression, ")", " ) ")
+	return strings.Fields(expression)
+}
+
+func (p *spdxParser) peek() string {
+	if p.position >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.position]
+}
+
+func (p *spdxParser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *spdxParser) orExpression() (spdxTerm, error) {
+	return p.binaryExpression(spdxOr, p.andExpression)
+}
+
+func (p *spdxParser) andExpression() (spdxTerm, error) {
+	return p.binaryExpression(spdxAnd, p.withExpression)
+}
+
+func (p *spdxParser) binaryExpression(operator string, operand func() (spdxTerm, error)) (spdxTerm, error) {
+	left, err := operand()
+	if err != nil {
+		return spdxTerm{}, err
+	}
+	if !strings.EqualFold(p.peek(), operator) {
+		return left, nil
+	}
+	term := spdxTerm{text: left.text, operator: operator, operands: []spdxTerm{left}}
+	for strings.EqualFold(p.peek(), operator) {
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return spdxTerm{}, err
+		}
+		term.text += " " + operator + " " + right.text
+		term.operands = append(term.operands, right)
+	}
+	return term, nil
+}
+
+func (p *spdxParser) withExpression() (spdxTerm, error) {
+	license, err := p.simpleExpression()
+	if err != nil {
+		return spdxTerm{}, err
+	}
+	if !strings.EqualFold(p.peek(), spdxWith) {
+		return license, nil
+	}
+	p.next()
+	exception := p.next()
+	if exception == "" || isSPDXKeyword(exception) || exception == "(" || exception == ")" {
+		return spdxTerm{}, errors.New("missing license exception after WITH in SPDX expression")
+	}
+	if known, found := spdxExceptions[strings.ToLower(exception)]; found {
+		exception = known
+	} else {
+		p.unknown = append(p.unknown, exception)
+	}
+	text := license.text + " " + spdxWith + " " + exception
+	return spdxTerm{text: text, license: text}, nil
+}
+
+func (p *spdxParser) simpleExpression() (spdxTerm, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return spdxTerm{}, errors.New("unexpected end of SPDX expression")
+	case token == "(":
+		expression, err := p.orExpression()
+		if err != nil {
+			return spdxTerm{}, err
+		}
+		if p.next() != ")" {
+			return spdxTerm{}, errors.New("missing closing parenthesis in SPDX expression")
+		}
+		expression.text = "(" + expression.text + ")"
+		return expression, nil
+	case token == ")" || isSPDXKeyword(token):
+		return spdxTerm{}, fmt.Errorf("unexpected %q in SPDX expression", token)
+	}
+	license := p.license(token)
+	return spdxTerm{text: license, license: license}, nil
+}
+
+// license returns the license identifier with its official case, or as is when it's not known
+func (p *spdxParser) license(identifier string) string {
+	if strings.HasPrefix(identifier, "LicenseRef-") || strings.HasPrefix(identifier, "DocumentRef-") {
+		// custom licenses are always valid
+		return identifier
+	}
+	if known, found := spdxLicenses[strings.ToLower(identifier)]; found {
+		return known
+	}
+	// "or later" version of a license
+	if base := strings.TrimSuffix(identifier, "+"); base != identifier {
+		if known, found := spdxLicenses[strings.ToLower(base)]; found {
+			return known + "+"
+		}
+	}
+	p.unknown = append(p.unknown, identifier)
+	return identifier
+}
+
+func isSPDXKeyword(token string) bool {
+	return strings.EqualFold(token, spdxAnd) || strings.EqualFold(token, spdxOr) || strings.EqualFold(token, spdxWith)
+}
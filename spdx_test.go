@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSPDXExpression(t *testing.T) {
+	testData := []struct {
+		expression string
+		normalized string
+		unknown    []string
+	}{
+		{"MIT", "MIT", []string{}},
+		{"mit", "MIT", []string{}},
+		{"apache-2.0  or   MIT", "Apache-2.0 OR MIT", []string{}},
+		{"GPL-2.0-or-later WITH classpath-exception-2.0", "GPL-2.0-or-later WITH Classpath-exception-2.0", []string{}},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", "(MIT OR Apache-2.0) AND BSD-3-Clause", []string{}},
+		{"(MIT or(Apache-2.0))", "(MIT OR (Apache-2.0))", []string{}},
+		{"LGPL-2.1+", "LGPL-2.1+", []string{}},
+		{"elastic-2.0 OR unicode-3.0", "Elastic-2.0 OR Unicode-3.0", []string{}},
+		{"Apache-2.0 WITH llvm-exception", "Apache-2.0 WITH LLVM-exception", []string{}},
+		{"LicenseRef-Proprietary", "LicenseRef-Proprietary", []string{}},
+		{"DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", "DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", []string{}},
+		{"MIT OR Unknown-1.0", "MIT OR Unknown-1.0", []string{"Unknown-1.0"}},
+		{"MIT WITH Unknown-exception", "MIT WITH Unknown-exception", []string{"Unknown-exception"}},
+	}
+	for _, testItem := range testData {
+		normalized, unknown, err := parseSPDXExpression(testItem.expression)
+		require.NoError(t, err, testItem.expression)
+		assert.Equal(t, testItem.normalized, normalized)
+		assert.Equal(t, testItem.unknown, unknown)
+	}
+}
+
+func TestSameSPDXExpression(t *testing.T) {
+	testData := []struct {
+		expression1 string
+		expression2 string
+		same        bool
+	}{
+		{"MIT", "mit", true},
+		{"Apache-2.0 OR MIT", "MIT OR Apache-2.0", true},
+		{"MIT AND (Apache-2.0 OR BSD-3-Clause)", "(BSD-3-Clause OR Apache-2.0) AND MIT", true},
+		{"(MIT OR Apache-2.0) OR BSD-3-Clause", "BSD-3-Clause OR MIT OR (Apache-2.0)", true},
+		{"GPL-2.0-or-later WITH Classpath-exception-2.0 OR MIT", "MIT OR GPL-2.0-or-later WITH Classpath-exception-2.0", true},
+		{"MIT OR Apache-2.0", "MIT AND Apache-2.0", false},
+		{"MIT AND Apache-2.0 OR BSD-3-Clause", "MIT AND (Apache-2.0 OR BSD-3-Clause)", false},
+		{"MIT OR Apache-2.0", "MIT OR", false},
+	}
+	for _, testItem := range testData {
+		assert.Equal(t, testItem.same, sameSPDXExpression(testItem.expression1, testItem.expression2), testItem)
+	}
+}
+
+func TestInvalidSPDXExpression(t *testing.T) {
+	for _, expression := range []string{"", "  ", "MIT OR", "AND MIT", "(MIT", "MIT)", "MIT Apache-2.0", "MIT WITH", "MIT WITH OR", "()"} {
+		_, _, err := parseSPDXExpression(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestFindSPDXExpression(t *testing.T) {
+	testData := []struct {
+		content    string
+		expression string
+		found      bool
+	}{
+		{"// SPDX-License-Identifier: MIT\npackage main\n", "MIT", true},
+		{"/* SPDX-License-Identifier: Apache-2.0 OR MIT */\n", "Apache-2.0 OR MIT", true},
+		{"<!-- SPDX-License-Identifier: MIT -->\n", "MIT", true},
+		{"# SPDX-License-Identifier:\tGPL-2.0-only\r\n", "GPL-2.0-only", true},
+		{"package main\n", "", false},
+	}
+	for _, testItem := range testData {
+		expression, found := findSPDXExpression([]byte(testItem.content))
+		assert.Equal(t, testItem.found, found)
+		assert.Equal(t, testItem.expression, expression)
+	}
+}
+
+func TestSPDXStatus(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("// Copyright TestCorp.\n// SPDX-License-Identifier: {{.SPDX}}\n"), 0600))
+	writeTestTree(t, dir, map[string]string{
+		"new.go":      testSource,
+		"same.go":     "// Copyright TestCorp.\n// SPDX-License-Identifier: mit or apache-2.0\n" + testSource,
+		"order.go":    "// Copyright TestCorp.\n// SPDX-License-Identifier: Apache-2.0 OR MIT\n" + testSource,
+		"mismatch.go": "// Copyright TestCorp.\n// SPDX-License-Identifier: MIT\n" + testSource,
+		"unknown.go":  "// Copyright TestCorp.\n// SPDX-License-Identifier: MIT OR Unknown-1.0\n" + testSource,
+		"invalid.go":  "// Copyright TestCorp.\n// SPDX-License-Identifier: MIT OR\n" + testSource,
+	})
+	profile := ConfigProfile{
+		Copyright: copyright,
+		SPDX:      "MIT OR Apache-2.0",
+	}
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+
+	results := NewResults()
+	for _, name := range []string{"new.go", "same.go", "order.go", "mismatch.go", "unknown.go", "invalid.go"} {
+		fileName := filepath.Join(dir, name)
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, results)
+	}
+	assert.Equal(t, 1, results.Len(fileStatusNoCopyright))
+	assert.Equal(t, 2, results.Len(fileStatusWithCopyright))
+	assert.Equal(t, 1, results.Len(fileStatusSPDXMismatch))
+	assert.Equal(t, 2, results.Len(fileStatusUnknownSPDX))
+	assert.Equal(t, exitCodeMissingHeader|exitCodeSPDX, checkExitCode(results))
+
+	content, err := os.ReadFile(filepath.Join(dir, "new.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "// Copyright TestCorp.\n// SPDX-License-Identifier: MIT OR Apache-2.0\n"+testSource, string(content))
+}
+
+func TestMissingSPDX(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("/*\n * Copyright (C) {{.Year}} TestCorp.\n * SPDX-License-Identifier: {{.SPDX}}\n */\n"), 0600))
+	writeTestTree(t, dir, map[string]string{
+		"file.go": "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource,
+		// an identifier in the code is not the one of the file
+		"fixture.go": "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource + "\nconst fixture = `\n// SPDX-License-Identifier: MIT\n`\n",
+	})
+
+	// the older notices without identifier are recognized too
+	profile := ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigLeaveYear), SPDX: "MIT", DetectOwn: `Copyright \(C\) (\d{4}) TestCorp`}
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+	results := NewResults()
+	for _, name := range []string{"file.go", "fixture.go"} {
+		fileName := filepath.Join(dir, name)
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, results)
+	}
+	assert.Equal(t, 2, results.Len(fileStatusMissingSPDX))
+}
+
+func TestSPDXInHeaderBlock(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte("/*\n * Copyright (C) {{.Year}} TestCorp.\n * SPDX-License-Identifier: {{.SPDX}}\n */\n"), 0600))
+	// the identifier is in the same comment, after the part detected by the pattern
+	fileName := filepath.Join(dir, "file.go")
+	require.NoError(t, os.WriteFile(fileName, []byte("/*\n * Copyright (C) 2015 TestCorp.\n * SPDX-License-Identifier: MIT\n */\n"+testSource), 0600))
+
+	profile := ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigLeaveYear), SPDX: "MIT", DetectOwn: `Copyright \(C\) (\d{4}) TestCorp`}
+	notice, err := loadNotice("test", profile)
+	require.NoError(t, err)
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	results := NewResults()
+	notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, results)
+	assert.Equal(t, 1, results.Len(fileStatusWithCopyright))
+}
+
+func TestInvalidProfileSPDX(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+
+	_, err := loadNotice("test", ConfigProfile{Copyright: copyright, SPDX: "MIT OR Unknown-1.0"})
+	assert.Error(t, err)
+	_, err = loadNotice("test", ConfigProfile{Copyright: copyright, SPDX: "(MIT"})
+	assert.Error(t, err)
+	// the template has no SPDX-License-Identifier line
+	_, err = loadNotice("test", ConfigProfile{Copyright: copyright, SPDX: "MIT"})
+	assert.ErrorContains(t, err, "SPDX-License-Identifier")
+}
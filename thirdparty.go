@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxThirdPartyLines is the maximum number of lines of a third-party header analyzed above its copyright line
+	maxThirdPartyLines = 200
+	licenseUnknown     = "unknown"
+	licensePublic      = "public-domain"
+)
+
+var (
+	// copyrightHolderPattern matches a line starting with a copyright followed by "(c)", "©" or a year, then the holder
+	copyrightHolderPattern = regexp.MustCompile(`(?i)^copyright(?:[ \t]*(?:\(c\)|©))*[ \t]*(` + yearExpression + `)?[ \t,]*(?:by[ \t]+)?(.*)$`)
+	allRightsReserved      = regexp.MustCompile(`(?i)[ \t,.]*all rights reserved\.?$`)
+	commentPrefix          = regexp.MustCompile(`^[ \t]*(?:/\*+|\*+|//+|#+|;+|--+|!|'|<!--|\(\*|{-|REM[ \t])?[ \t]*`)
+	commentSuffix          = regexp.MustCompile(`[ \t]*(?:\*+/|-->|\*\)|-})[ \t]*$`)
+	whitespaces            = regexp.MustCompile(`\s+`)
+	licenseVersion         = regexp.MustCompile(`version ([0-9](?:\.[0-9])?)`)
+)
+
+// licenseFamily is a license recognized from the text of a header
+type licenseFamily struct {
+	name     string
+	patterns []*regexp.Regexp // all the patterns must match the text
+	excludes []*regexp.Regexp // none of these patterns must match the text
+	versions bool             // the version found in the text is added to the name
+}
+
+// licenseFamilies are tested in order: the more specific licenses come first
+var licenseFamilies = []licenseFamily{
+	{name: "Apache-2.0", patterns: textPatterns(`apache license,? version 2\.0`)},
+	{name: "AGPL", patterns: textPatterns(`gnu affero general public license`), versions: true},
+	{name: "LGPL", patterns: textPatterns(`gnu (?:lesser|library) general public license`), versions: true},
+	{name: "GPL", patterns: textPatterns(`gnu general public license`), versions: true},
+	{name: "MPL-2.0", patterns: textPatterns(`mozilla public license,? v(?:ersion|\.)? ?2\.0`)},
+	{name: "MPL", patterns: textPatterns(`mozilla public license`)},
+	{name: "BSD-3-Clause", patterns: textPatterns(`redistribution and use in source and binary forms`, `may (?:not )?be used to endorse or promote`)},
+	{name: "BSD-2-Clause", patterns: textPatterns(`redistribution and use in source and binary forms`)},
+	{name: "BSD", patterns: textPatterns(`\bbsd-style license\b`)},
+	{name: "MIT", patterns: textPatterns(`permission is hereby granted, free of charge`)},
+	{name: "MIT", patterns: textPatterns(`\bmit license\b`)},
+	{name: licensePublic, patterns: textPatterns(`(?:placed|released|dedicated) (?:in|into|to) the public domain`), excludes: textPatterns(`\bnot\b.{0,20}public domain`)},
+}
+
+func textPatterns(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for index, pattern := range patterns {
+		compiled[index] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// ThirdPartyHeader is what was recognized in a copyright header which is not our own
+type ThirdPartyHeader struct {
+	License string   `json:"license"`
+	Holders []string `json:"holders,omitempty"`
+	Years   []string `json:"years,omitempty"`
+}
+
+// classifyThirdPartyHeader extracts the holders, the years and the license from the block of lines
+// around the copyright found at this position in the content
+func classifyThirdPartyHeader(content []byte, position int) *ThirdPartyHeader {
+	lines := headerBlock(content, position)
+	header := &ThirdPartyHeader{
+		License: licenseUnknown,
+		Holders: make([]string, 0),
+		Years:   make([]string, 0),
+	}
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = commentSuffix.ReplaceAllString(commentPrefix.ReplaceAllString(line, ""), "")
+		text = append(text, line)
+		match := copyrightHolderPattern.FindStringSubmatch(line)
+		if match == nil || (match[1] == "" && !hasCopyrightSign(line)) {
+			// most likely some text about "copyright notices" in the license
+			continue
+		}
+		if match[1] != "" {
+			header.Years = append(header.Years, match[1])
+		}
+		holder := strings.TrimRight(allRightsReserved.ReplaceAllString(match[2], ""), " \t.,;")
+		if holder != "" && !contains(header.Holders, holder) {
+			header.Holders = append(header.Holders, holder)
+		}
+	}
+	if expression, found := findSPDXExpression([]byte(strings.Join(lines, "\n"))); found {
+		// the license is declared
+		header.License = expression
+		return header
+	}
+	header.License = recognizeLicense(strings.ToLower(whitespaces.ReplaceAllString(strings.Join(text, " "), " ")))
+	return header
+}
+
+// recognizeLicense returns the license family of the text (in lowercase), or "unknown"
+func recognizeLicense(text string) string {
+	for _, family := range licenseFamilies {
+		if !matchAll(family.patterns, []byte(text)) || matchAny(family.excludes, []byte(text)) {
+			continue
+		}
+		if family.versions {
+			if version := licenseVersion.FindStringSubmatch(text); version != nil {
+				if !strings.Contains(version[1], ".") {
+					// "version 2 of the License"
+					return family.name + "-" + version[1] + ".0"
+				}
+				return family.name + "-" + version[1]
+			}
+		}
+		return family.name
+	}
+	return licenseUnknown
+}
+
+// headerBlock returns the lines around the position, up to the blank lines before and after them
+func headerBlock(content []byte, position int) []string {
+	// the generic pattern also matches the blank space in front of the copyright
+	for position < len(content) && bytes.IndexByte([]byte(" \t\r\n"), content[position]) > -1 {
+		position++
+	}
+	start := bytes.LastIndexByte(content[:position], '\n') + 1
+	for count := 0; start > 0 && count < maxThirdPartyLines; count++ {
+		previous := bytes.LastIndexByte(content[:start-1], '\n') + 1
+		if len(bytes.TrimSpace(content[previous:start])) == 0 {
+			break
+		}
+		start = previous
+	}
+
+	lines := make([]string, 0)
+	for position = start; position < len(content) && len(lines) < 2*maxThirdPartyLines; {
+		var line []byte
+		line, position = nextLine(content, position)
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+func hasCopyrightSign(line string) bool {
+	lower := strings.ToLower(line)
+	return strings.Contains(lower, "(c)") || strings.Contains(line, "©")
+}
+
+func matchAll(rules []*regexp.Regexp, text []byte) bool {
+	for _, rule := range rules {
+		if !rule.Match(text) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mitHeader = `/*
+ * Copyright (c) 2018 Jane Doe
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ */
+`
+	bsd3Header = `// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright (c) 2012, 2014-2016 Someone Else
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//    * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+`
+	bsd2Header = `# Copyright (C) 2015 Foo Ltd.
+#
+# Redistribution and use in source and binary forms, with or without
+# modification, are permitted provided that the following conditions are met:
+`
+	bsdStyleHeader = `// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+`
+	apacheHeader = `/*
+ * Copyright 2015-2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ */
+`
+	gplHeader = `/*
+ * Copyright (C) 2001 Free Software Foundation, Inc.
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * (at your option) any later version.
+ */
+`
+	lgplHeader = `/* Copyright (C) 1991-2020 Free Software Foundation, Inc.
+   The GNU C Library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version. */
+`
+	agplHeader = `// Copyright (C) 2019 Some Company
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License.
+`
+	mplHeader = `/* Copyright (c) 2017 Mozilla
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+`
+	publicDomainHeader = `// Copyright (c) 2005 Anonymous
+// This code was placed in the public domain.
+`
+	spdxHeader = `// Copyright 2021 Acme Corp.
+// SPDX-License-Identifier: Apache-2.0 OR MIT
+`
+	unknownHeader = `// Copyright 2021 Acme Corp.
+// Proprietary and confidential.
+`
+)
+
+func TestClassifyThirdPartyHeader(t *testing.T) {
+	testData := []struct {
+		content string
+		header  ThirdPartyHeader
+	}{
+		{mitHeader, ThirdPartyHeader{License: "MIT", Holders: []string{"Jane Doe"}, Years: []string{"2018"}}},
+		{bsd3Header, ThirdPartyHeader{License: "BSD-3-Clause", Holders: []string{"The Go Authors", "Someone Else"}, Years: []string{"2009", "2012, 2014-2016"}}},
+		{bsd2Header, ThirdPartyHeader{License: "BSD-2-Clause", Holders: []string{"Foo Ltd"}, Years: []string{"2015"}}},
+		{bsdStyleHeader, ThirdPartyHeader{License: "BSD", Holders: []string{"The Go Authors"}, Years: []string{"2012"}}},
+		{apacheHeader, ThirdPartyHeader{License: "Apache-2.0", Holders: []string{"Google LLC"}, Years: []string{"2015-2020"}}},
+		{gplHeader, ThirdPartyHeader{License: "GPL-2.0", Holders: []string{"Free Software Foundation, Inc"}, Years: []string{"2001"}}},
+		{lgplHeader, ThirdPartyHeader{License: "LGPL-2.1", Holders: []string{"Free Software Foundation, Inc"}, Years: []string{"1991-2020"}}},
+		{agplHeader, ThirdPartyHeader{License: "AGPL-3.0", Holders: []string{"Some Company"}, Years: []string{"2019"}}},
+		{mplHeader, ThirdPartyHeader{License: "MPL-2.0", Holders: []string{"Mozilla"}, Years: []string{"2017"}}},
+		{publicDomainHeader, ThirdPartyHeader{License: licensePublic, Holders: []string{"Anonymous"}, Years: []string{"2005"}}},
+		{spdxHeader, ThirdPartyHeader{License: "Apache-2.0 OR MIT", Holders: []string{"Acme Corp"}, Years: []string{"2021"}}},
+		{unknownHeader, ThirdPartyHeader{License: licenseUnknown, Holders: []string{"Acme Corp"}, Years: []string{"2021"}}},
+	}
+	for _, testItem := range testData {
+		content := []byte("package main\n\n" + testItem.content + "\n" + testSource)
+		generic := detectGenericCopyright.FindIndex(content)
+		if assert.NotNil(t, generic) {
+			assert.Equal(t, &testItem.header, classifyThirdPartyHeader(content, generic[0]))
+		}
+	}
+}
+
+func TestHeaderBlock(t *testing.T) {
+	content := []byte("package main\n\n// line 1\n// Copyright 2020 Someone\n// line 3\n\nfunc main() {}\n")
+	assert.Equal(t, []string{"// line 1", "// Copyright 2020 Someone", "// line 3"}, headerBlock(content, 20))
+	assert.Equal(t, []string{"package main"}, headerBlock(content, 0))
+}
+
+func TestOtherCopyrightClassified(t *testing.T) {
+	dir := t.TempDir()
+	copyright := filepath.Join(dir, "copyright.txt")
+	require.NoError(t, os.WriteFile(copyright, []byte(testTemplate), 0600))
+	fileName := filepath.Join(dir, "vendored.go")
+	require.NoError(t, os.WriteFile(fileName, []byte(apacheHeader+testSource), 0600))
+
+	notice, err := loadNotice("test", ConfigProfile{Copyright: copyright, Year: yearPolicy(ConfigLeaveYear)})
+	require.NoError(t, err)
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	results := NewResults()
+	notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, results)
+
+	others := results.Get(fileStatusOtherCopyright)
+	require.Len(t, others, 1)
+	assert.Equal(t, &ThirdPartyHeader{License: "Apache-2.0", Holders: []string{"Google LLC"}, Years: []string{"2015-2020"}}, others[0].thirdParty)
+}
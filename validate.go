@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// configValues are the values accepted by the enumerated options of a profile
+	configValues = map[string][]string{
+		"year":        {"none", "leave", "update"},
+		"year-range":  {"extend", "replace"},
+		"year-source": {"clock", "git-first-commit", "git-last-commit", "mtime"},
+		"utf8-bom":    {"forget", "keep", "add"},
+	}
+
+	configErrorLine = regexp.MustCompile(`line ([0-9]+): (.*)`)
+	configBooleans  = []string{"true", "false", "yes", "no", "on", "off", "y", "n"}
+)
+
+// runValidateConfig runs the validate-config command: it displays the problems found in the configuration file
+func runValidateConfig(filename string) int {
+	problems := validateConfig(filename)
+	for _, problem := range problems {
+		clog.Error(problem)
+	}
+	if len(problems) > 0 {
+		clog.Errorf("%d %s found in configuration", len(problems), simplePlural("problem", len(problems)))
+		return exitCodeFailure
+	}
+	clog.Infof("configuration file '%s' is valid", filename)
+	return 0
+}
+
+// configProblem is a problem found in a configuration file, at a line and column (starting at 1) when known
+type configProblem struct {
+	file    string
+	line    int
+	column  int
+	message string
+}
+
+func (p configProblem) String() string {
+	position := p.file
+	if p.line > 0 {
+		position += ":" + strconv.Itoa(p.line)
+		if p.column > 0 {
+			position += ":" + strconv.Itoa(p.column)
+		}
+	}
+	return position + ": " + p.message
+}
+
+// configValidator checks a configuration file and the files it includes
+type configValidator struct {
+	problems []configProblem
+	visited  map[string]bool
+	profiles map[string]ConfigProfile
+	resolved bool // the profiles were loaded and merged successfully
+}
+
+// validateConfig returns all the problems found in the configuration file, sorted by position
+func validateConfig(filename string) []configProblem {
+	validator := &configValidator{
+		problems: make([]configProblem, 0),
+		visited:  make(map[string]bool),
+	}
+	config, err := LoadFileConfig(filename)
+	validator.profiles = config.Profiles
+	validator.resolved = err == nil
+	validator.validateFile(filename, false)
+	if err != nil && len(validator.problems) == 0 {
+		// a problem the validation doesn't know about, like a cycle in the profiles
+		validator.problems = append(validator.problems, configProblem{file: filename, message: err.Error()})
+	}
+
+	sort.SliceStable(validator.problems, func(i, j int) bool {
+		a, b := validator.problems[i], validator.problems[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.column < b.column
+	})
+	return validator.problems
+}
+
+// addError adds a problem from a YAML error, at its line when available
+func (v *configValidator) addError(file string, err error) {
+	problem := configProblem{file: file, message: err.Error()}
+	if match := configErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		problem.line, _ = strconv.Atoi(match[1])
+		problem.message = match[2]
+	}
+	v.problems = append(v.problems, problem)
+}
+
+func (v *configValidator) add(file string, node *yaml.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, configProblem{
+		file:    file,
+		line:    node.Line,
+		column:  node.Column,
+		message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateFile checks a configuration file, and the files it includes
+func (v *configValidator) validateFile(filename string, included bool) {
+	absFilename := absolutePath(filename)
+	if v.visited[absFilename] {
+		return
+	}
+	v.visited[absFilename] = true
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		v.problems = append(v.problems, configProblem{file: filename, message: err.Error()})
+		return
+	}
+	document := &yaml.Node{}
+	err = yaml.Unmarshal(content, document)
+	if err != nil {
+		v.addError(filename, err)
+		return
+	}
+	if len(document.Content) == 0 {
+		return
+	}
+	root := resolveAlias(document.Content[0])
+	if root.Kind != yaml.MappingNode {
+		v.add(filename, root, "the configuration must be a map")
+		return
+	}
+	fields := yamlFields(reflect.TypeOf(Config{}))
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		key, value := root.Content[index], resolveAlias(root.Content[index+1])
+		fieldType, found := fields[key.Value]
+		switch {
+		case !found:
+			v.add(filename, key, "unknown key '%s'", key.Value)
+		case !v.validateType(filename, key, value, fieldType):
+			continue
+		case key.Value == "include":
+			for _, include := range scalars(value) {
+				includeFile := os.ExpandEnv(include.Value)
+				if !filepath.IsAbs(includeFile) {
+					includeFile = filepath.Join(filepath.Dir(filename), includeFile)
+				}
+				if _, err := os.Stat(includeFile); err != nil {
+					v.add(filename, include, "cannot include '%s': %s", include.Value, err)
+					continue
+				}
+				v.validateFile(includeFile, true)
+			}
+		case key.Value == "defaults":
+			if included {
+				v.add(filename, key, "the defaults of an included file are ignored: move them to the main configuration file")
+			}
+			v.validateProfile(filename, "", value)
+		case key.Value == "profiles":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for profile := 0; profile+1 < len(value.Content); profile += 2 {
+				name, profileNode := value.Content[profile], resolveAlias(value.Content[profile+1])
+				if profileNode.Kind != yaml.MappingNode && profileNode.Tag != "!!null" {
+					v.add(filename, profileNode, "profile '%s' must be a map", name.Value)
+					continue
+				}
+				v.validateProfile(filename, name.Value, profileNode)
+				v.validateRequired(filename, name)
+			}
+		}
+	}
+}
+
+// validateProfile checks the keys and the values of a profile (or of the defaults when the name is empty)
+func (v *configValidator) validateProfile(file, name string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		// empty profile
+		return
+	}
+	fields := yamlFields(reflect.TypeOf(ConfigProfile{}))
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		key, value := node.Content[index], resolveAlias(node.Content[index+1])
+		fieldType, found := fields[key.Value]
+		if !found {
+			v.add(file, key, "unknown key '%s'", key.Value)
+			continue
+		}
+		if !v.validateType(file, key, value, fieldType) {
+			continue
+		}
+		if values, found := configValues[key.Value]; found {
+			if value.Kind == yaml.ScalarNode && !containsFold(values, value.Value) {
+				v.add(file, value, "invalid value '%s' for '%s': expected %s", value.Value, key.Value, strings.Join(values, ", "))
+			}
+			continue
+		}
+		switch key.Value {
+		case "extends":
+			if name == "" {
+				v.add(file, key, "the defaults cannot extend a profile")
+			} else if _, found := v.profiles[value.Value]; !found {
+				v.add(file, value, "unknown profile '%s'", value.Value)
+			}
+		case "source":
+			for _, source := range scalars(value) {
+				if info, err := os.Stat(os.ExpandEnv(source.Value)); err != nil {
+					v.add(file, source, "cannot find source '%s': %s", source.Value, err)
+				} else if !info.IsDir() {
+					v.add(file, source, "source '%s' is not a directory", source.Value)
+				}
+			}
+		case "extensions":
+			for _, extension := range scalars(value) {
+				if strings.Trim(extension.Value, ". \t") == "" {
+					v.add(file, extension, "empty file extension")
+				}
+			}
+		case "copyright", "replaces":
+			for _, template := range scalars(value) {
+				if _, err := loadCopyrightTemplate(template.Value); err != nil {
+					v.add(file, template, "cannot load copyright template: %s", err)
+				}
+			}
+		case "exclude-from":
+			if _, err := readLines(value.Value); err != nil {
+				v.add(file, value, "cannot read exclusion file: %s", err)
+			}
+		case "detect-own", "detect-others":
+			if _, err := regexp.Compile(value.Value); err != nil {
+				v.add(file, value, "invalid regexp in '%s': %s", key.Value, err)
+			}
+		case "preambles":
+			for rule := 0; rule+1 < len(value.Content); rule += 2 {
+				for _, pattern := range scalars(resolveAlias(value.Content[rule+1])) {
+					if _, err := regexp.Compile(pattern.Value); err != nil {
+						v.add(file, pattern, "invalid preamble regexp: %s", err)
+					}
+				}
+			}
+		case "comment-style":
+			if _, found := commentStyles[value.Value]; !found && value.Value != commentStyleNone && value.Value != commentStyleAuto {
+				v.add(file, value, "unknown comment style '%s'", value.Value)
+			}
+		case "spdx":
+			if _, unknown, err := parseSPDXExpression(value.Value); err != nil {
+				v.add(file, value, "invalid SPDX expression: %s", err)
+			} else if len(unknown) > 0 {
+				v.add(file, value, "unknown SPDX identifier: %s", strings.Join(unknown, ", "))
+			}
+		case "commit-message":
+			if _, err := getCommitMessage(value.Value, CommitMessageData{}); err != nil {
+				v.add(file, value, "%s", err)
+			}
+		}
+	}
+}
+
+// validateRequired checks the profile has the fields needed to run, once merged with the profile it extends or the defaults
+func (v *configValidator) validateRequired(file string, name *yaml.Node) {
+	profile, found := v.profiles[name.Value]
+	if !found || !v.resolved {
+		return
+	}
+	if profile.Source == nil || len(*profile.Source) == 0 {
+		v.add(file, name, "profile '%s' has no source", name.Value)
+	}
+	if profile.Extensions == nil || len(*profile.Extensions) == 0 {
+		v.add(file, name, "profile '%s' has no extensions", name.Value)
+	}
+	if profile.Copyright == "" {
+		v.add(file, name, "profile '%s' has no copyright template", name.Value)
+	}
+}
+
+// validateType checks the node can be loaded into a field of this type. It returns false when it can't
+func (v *configValidator) validateType(file string, key, node *yaml.Node, fieldType reflect.Type) bool {
+	if node.Tag == "!!null" {
+		return false
+	}
+	expected := ""
+	if fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Bool {
+		// an option which can be disabled in a profile
+		fieldType = fieldType.Elem()
+	}
+	switch {
+	case fieldType == reflect.TypeOf(&StringSlice{}):
+		if node.Kind == yaml.ScalarNode {
+			return true
+		}
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				if resolveAlias(item).Kind != yaml.ScalarNode {
+					v.add(file, item, "'%s' must be a list of strings", key.Value)
+					return false
+				}
+			}
+			return true
+		}
+		expected = "a string or a list of strings"
+	case fieldType.Kind() == reflect.Map || fieldType.Kind() == reflect.Struct:
+		if node.Kind == yaml.MappingNode {
+			return true
+		}
+		expected = "a map"
+	case fieldType.Kind() == reflect.Bool:
+		if node.Kind == yaml.ScalarNode && containsFold(configBooleans, node.Value) {
+			return true
+		}
+		expected = "true or false"
+	case fieldType.Kind() == reflect.Int || fieldType.Kind() == reflect.Int64:
+		if _, err := strconv.ParseInt(node.Value, 10, 64); node.Kind == yaml.ScalarNode && err == nil {
+			return true
+		}
+		expected = "a number"
+	default:
+		if node.Kind == yaml.ScalarNode {
+			return true
+		}
+		expected = "a string"
+	}
+	v.add(file, node, "'%s' must be %s", key.Value, expected)
+	return false
+}
+
+// yamlFields returns the type of the fields of the struct, by their yaml key
+func yamlFields(structType reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, structType.NumField())
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag != "" && tag != "-" {
+			fields[tag] = field.Type
+		}
+	}
+	return fields
+}
+
+// scalars returns the scalar values of a StringSlice field: a single value or a list of values
+func scalars(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.ScalarNode {
+		return []*yaml.Node{node}
+	}
+	values := make([]*yaml.Node, 0, len(node.Content))
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			if item = resolveAlias(item); item.Kind == yaml.ScalarNode && item.Value != replaceMarker {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
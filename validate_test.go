@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemStrings returns the problems as strings, with the file names relative to the directory
+func problemStrings(t *testing.T, dir string, problems []configProblem) []string {
+	t.Helper()
+	list := make([]string, len(problems))
+	for index, problem := range problems {
+		relative, err := filepath.Rel(dir, problem.file)
+		require.NoError(t, err)
+		problem.file = filepath.ToSlash(relative)
+		list[index] = problem.String()
+	}
+	return list
+}
+
+func TestValidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"src/main.go":   testSource,
+		"copyright.txt": testTemplate,
+	})
+	copyright := filepath.Join(dir, "copyright.txt")
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "defaults:\n  copyright: " + copyright + "\n  year: update\nprofiles:\n  test:\n    source: " + filepath.Join(dir, "src") +
+		"\n    extensions: [go]\n    utf8-bom: keep\n    detect-own: '^Copyright'\n    spdx: MIT\n  builtin:\n    extends: test\n    copyright: builtin:mit\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	assert.Empty(t, validateConfig(configFile))
+	assert.Equal(t, 0, runCommand(t, "--config", configFile, commandValidateConfig))
+}
+
+func TestValidateConfigProblems(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	writeTestTree(t, dir, map[string]string{
+		"src/main.go":   testSource,
+		"copyright.txt": testTemplate,
+		"config.yaml": `include: other.yaml
+unknown-option: 1
+workers: many
+defaults:
+  year: sometimes
+profiles:
+  test:
+    source: ` + src + `
+    extensions: ["go", ""]
+    copyright: not-found.txt
+    detect-own: '(unclosed'
+    colour: blue
+  bad-values:
+    source: [` + src + `, ` + filepath.Join(dir, "missing") + `]
+    extensions: go
+    copyright: builtin:unknown
+    comment-style: fancy
+    spdx: MIT OR Unknown-1.0
+    exclude-gitignore: maybe
+    variables: [holder]
+  missing:
+    extends: nobody
+  scalar: 1
+`,
+		"other.yaml": "profiles:\n  included:\n    source: " + src + "\n    extensions: go\n    copyright: " + filepath.Join(dir, "copyright.txt") + "\n    utf8-bom: drop\n",
+	})
+
+	problems := validateConfig(filepath.Join(dir, "config.yaml"))
+	assert.Equal(t, []string{
+		"config.yaml:2:1: unknown key 'unknown-option'",
+		"config.yaml:3:10: 'workers' must be a number",
+		"config.yaml:5:9: invalid value 'sometimes' for 'year': expected none, leave, update",
+		"config.yaml:9:24: empty file extension",
+		"config.yaml:10:16: cannot load copyright template: open not-found.txt: no such file or directory",
+		"config.yaml:11:17: invalid regexp in 'detect-own': error parsing regexp: missing closing ): `(unclosed`",
+		"config.yaml:12:5: unknown key 'colour'",
+		"config.yaml:14:" + strconv.Itoa(len(src)+16) + ": cannot find source '" + filepath.Join(dir, "missing") + "': stat " + filepath.Join(dir, "missing") + ": no such file or directory",
+		"config.yaml:16:16: cannot load copyright template: unknown built-in template 'unknown' (available: apache-2.0, bsd-3-clause, gpl-3.0-or-later, mit, mpl-2.0, proprietary)",
+		"config.yaml:17:20: unknown comment style 'fancy'",
+		"config.yaml:18:11: unknown SPDX identifier: Unknown-1.0",
+		"config.yaml:19:24: 'exclude-gitignore' must be true or false",
+		"config.yaml:20:16: 'variables' must be a map",
+		"config.yaml:22:14: unknown profile 'nobody'",
+		"config.yaml:23:11: profile 'scalar' must be a map",
+		"other.yaml:6:15: invalid value 'drop' for 'utf8-bom': expected forget, keep, add",
+	}, problemStrings(t, dir, problems))
+
+	assert.Equal(t, exitCodeFailure, runCommand(t, "--config", filepath.Join(dir, "config.yaml"), commandValidateConfig))
+}
+
+func TestValidateConfigSyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"config.yaml": "profiles:\n  test:\n    source: [unclosed\n",
+		"main.yaml":   "include: config.yaml\n",
+	})
+	problems := validateConfig(filepath.Join(dir, "main.yaml"))
+	require.Len(t, problems, 1)
+	assert.Equal(t, "config.yaml", filepath.Base(problems[0].file))
+	assert.Greater(t, problems[0].line, 0)
+}
+
+func TestValidateConfigCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"config.yaml": "profiles:\n  first:\n    extends: second\n  second:\n    extends: first\n",
+	})
+	problems := validateConfig(filepath.Join(dir, "config.yaml"))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].message, "cycle")
+}
+
+func TestEmptyExtensionIsIgnored(t *testing.T) {
+	config, err := LoadConfig(bytes.NewBufferString("profiles:\n  test:\n    extensions: [\"\", go, \".\"]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, StringSlice{".go"}, *config.Profiles["test"].Extensions)
+}
+
+func TestValidateRequiredFields(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"config.yaml": "profiles:\n  empty:\n  no-copyright:\n    source: .\n    extensions: go\n",
+	})
+	problems := validateConfig(filepath.Join(dir, "config.yaml"))
+	assert.Equal(t, []string{
+		"config.yaml:2:3: profile 'empty' has no source",
+		"config.yaml:2:3: profile 'empty' has no extensions",
+		"config.yaml:2:3: profile 'empty' has no copyright template",
+		"config.yaml:3:3: profile 'no-copyright' has no copyright template",
+	}, problemStrings(t, dir, problems))
+}
+
+func TestValidateIncludedDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"config.yaml": "include: shared.yaml\ndefaults:\n  source: .\n  extensions: go\n  copyright: builtin:mit\n  variables:\n    holder: TestCorp\n",
+		"shared.yaml": "defaults:\n  year: leave\nprofiles:\n  shared:\n",
+	})
+	problems := validateConfig(filepath.Join(dir, "config.yaml"))
+	assert.Equal(t, []string{
+		"shared.yaml:1:1: the defaults of an included file are ignored: move them to the main configuration file",
+	}, problemStrings(t, dir, problems))
+}
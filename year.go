@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	yearPattern = regexp.MustCompile(`[\d]{4}`)
+)
+
+// lastYear returns the most recent year of a year expression like "2015, 2018-2020"
+func lastYear(expression string) (int, error) {
+	years := yearPattern.FindAllString(expression, -1)
+	if len(years) == 0 {
+		return 0, fmt.Errorf("no year found in %q", expression)
+	}
+	last := 0
+	for _, value := range years {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, err
+		}
+		if year > last {
+			last = year
+		}
+	}
+	return last, nil
+}
+
+// updateYearExpression returns the year expression brought up to date with the current year.
+// The boolean is false when the expression didn't need any update.
+func updateYearExpression(expression string, currentYear int, policy ConfigYearRange) (string, bool, error) {
+	last, err := lastYear(expression)
+	if err != nil {
+		return expression, false, err
+	}
+	if last >= currentYear {
+		return expression, false, nil
+	}
+	if policy == ConfigReplaceYearRange {
+		return strconv.Itoa(currentYear), true, nil
+	}
+	// extend the end of the last range of the list
+	start := strings.LastIndex(expression, ",") + 1
+	if dash := strings.LastIndex(expression, "-"); dash >= start {
+		return expression[:dash+1] + strings.Repeat(" ", countSpacesAfter(expression, dash)) + strconv.Itoa(currentYear), true, nil
+	}
+	return expression + "-" + strconv.Itoa(currentYear), true, nil
+}
+
+// countSpacesAfter returns the number of spaces or tabs found after the index
+func countSpacesAfter(text string, index int) int {
+	count := 0
+	for i := index + 1; i < len(text) && (text[i] == ' ' || text[i] == '\t'); i++ {
+		count++
+	}
+	return count
+}
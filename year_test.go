@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateYearExpression(t *testing.T) {
+	testData := []struct {
+		expression string
+		policy     ConfigYearRange
+		expected   string
+		changed    bool
+	}{
+		{"2020", ConfigExtendYearRange, "2020", false},
+		{"2021", ConfigExtendYearRange, "2021", false},
+		{"2015", ConfigExtendYearRange, "2015-2020", true},
+		{"2015-2019", ConfigExtendYearRange, "2015-2020", true},
+		{"2015 - 2019", ConfigExtendYearRange, "2015 - 2020", true},
+		{"2015-2020", ConfigExtendYearRange, "2015-2020", false},
+		{"2015, 2017", ConfigExtendYearRange, "2015, 2017-2020", true},
+		{"2015, 2017-2018", ConfigExtendYearRange, "2015, 2017-2020", true},
+		{"2019, 2021-2023", ConfigExtendYearRange, "2019, 2021-2023", false},
+		{"2015", ConfigReplaceYearRange, "2020", true},
+		{"2015-2019", ConfigReplaceYearRange, "2020", true},
+		{"2015, 2017-2018", ConfigReplaceYearRange, "2020", true},
+		{"2020", ConfigReplaceYearRange, "2020", false},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.expression, func(t *testing.T) {
+			expression, changed, err := updateYearExpression(testItem.expression, 2020, testItem.policy)
+			require.NoError(t, err)
+			assert.Equal(t, testItem.expected, expression)
+			assert.Equal(t, testItem.changed, changed)
+		})
+	}
+}
+
+func TestUpdateYearExpressionWithoutYear(t *testing.T) {
+	_, _, err := updateYearExpression("twenty", 2020, ConfigExtendYearRange)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creativeprojects/clog"
+)
+
+// fileYears are the years a file was created and last modified
+type fileYears struct {
+	first int
+	last  int
+}
+
+// yearResolver returns the copyright years of each file from the year source.
+// It is safe for concurrent use
+type yearResolver struct {
+	source    ConfigYearSource
+	now       func() time.Time
+	mu        sync.Mutex
+	roots     map[string]string               // git repository root of each directory
+	histories map[string]map[string]fileYears // years of each file, by repository root
+}
+
+func newYearResolver(source ConfigYearSource) *yearResolver {
+	return &yearResolver{
+		source:    source,
+		now:       time.Now,
+		roots:     make(map[string]string),
+		histories: make(map[string]map[string]fileYears),
+	}
+}
+
+// years returns the copyright years of the file. The current year is used when the source has no information about the file
+func (r *yearResolver) years(fileName string) CopyrightData {
+	currentYear := r.now().Year()
+	data := CopyrightData{FirstYear: currentYear, Year: currentYear}
+	switch r.source {
+	case ConfigYearFromModTime:
+		info, err := os.Stat(fileName)
+		if err != nil {
+			clog.Debugf("cannot read modification time of '%s': %s", fileName, err)
+			return data
+		}
+		year := info.ModTime().Year()
+		return CopyrightData{FirstYear: year, Year: year}
+	case ConfigYearFromGitFirstCommit, ConfigYearFromGitLastCommit:
+		years, found := r.gitYears(fileName)
+		if !found {
+			// a file not committed yet is created now
+			return data
+		}
+		if r.source == ConfigYearFromGitFirstCommit {
+			return CopyrightData{FirstYear: years.first, Year: years.first}
+		}
+		return CopyrightData{FirstYear: years.first, Year: years.last}
+	default:
+		return data
+	}
+}
+
+// gitYears returns the years of the first and last commits of the file
+func (r *yearResolver) gitYears(fileName string) (fileYears, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	directory := filepath.Dir(fileName)
+	root, found := r.roots[directory]
+	if !found {
+		var err error
+		root, err = gitRoot(directory)
+		if err != nil {
+			clog.Debugf("no git history for '%s': %s", fileName, err)
+		}
+		r.roots[directory] = root
+	}
+	if root == "" {
+		return fileYears{}, false
+	}
+	history, found := r.histories[root]
+	if !found {
+		var err error
+		history, err = loadGitHistory(root)
+		if err != nil {
+			clog.Warningf("cannot load git history of repository '%s': %s", root, err)
+		}
+		r.histories[root] = history
+	}
+	relative, err := gitRelativePath(root, fileName)
+	if err != nil {
+		return fileYears{}, false
+	}
+	years, found := history[relative]
+	return years, found
+}
+
+// loadGitHistory reads the whole history of the repository and returns the years of the first and last commits of each file
+func loadGitHistory(root string) (map[string]fileYears, error) {
+	output, err := git(root, "-c", "core.quotepath=off", "log", "--no-renames", "--format=%x01%ad", "--date=format:%Y", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return parseGitHistory(output), nil
+}
+
+// parseGitHistory parses the output of git log: each commit starts with a line "\x01year" followed by the files of the commit.
+func parseGitHistory(output string) map[string]fileYears {
+	history := make(map[string]fileYears)
+	year := 0
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == '\x01' {
+			year, _ = strconv.Atoi(line[1:])
+			continue
+		}
+		if year == 0 {
+			continue
+		}
+		// the author dates are not always in order
+		years, found := history[line]
+		if !found || year > years.last {
+			years.last = year
+		}
+		if !found || year < years.first {
+			years.first = year
+		}
+		history[line] = years
+	}
+	return history
+}
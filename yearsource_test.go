@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitHistory(t *testing.T) {
+	output := "\x012021\n\nmain.go\nsub/file.go\n\x012019\n\nmain.go\n\x012020\n\nsub/file.go\nold.go\n\x012018\n\nmain.go\n"
+	history := parseGitHistory(output)
+	assert.Equal(t, map[string]fileYears{
+		"main.go":     {first: 2018, last: 2021},
+		"sub/file.go": {first: 2020, last: 2021},
+		"old.go":      {first: 2020, last: 2020},
+	}, history)
+}
+
+// commitFile writes the file and commits it with an author date in this year
+func commitFile(t *testing.T, dir, name, content string, year int) {
+	t.Helper()
+	writeTestTree(t, dir, map[string]string{name: content})
+	t.Setenv("GIT_AUTHOR_DATE", strconv.Itoa(year)+"-06-15T12:00:00")
+	_, err := git(dir, "add", name)
+	require.NoError(t, err)
+	_, err = git(dir, "commit", "--quiet", "--message", "commit "+name)
+	require.NoError(t, err)
+}
+
+func TestYearResolver(t *testing.T) {
+	dir := initGitRepository(t)
+	commitFile(t, dir, "main.go", testSource, 2015)
+	commitFile(t, dir, "main.go", testSource+"\n", 2018)
+	commitFile(t, dir, "pkg/file.go", testSource, 2020)
+	writeTestTree(t, dir, map[string]string{"new.go": testSource})
+	modTime := time.Date(2012, 3, 1, 0, 0, 0, 0, time.Local)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "new.go"), modTime, modTime))
+
+	testData := []struct {
+		source ConfigYearSource
+		file   string
+		data   CopyrightData
+	}{
+		{ConfigYearFromClock, "main.go", CopyrightData{FirstYear: 2030, Year: 2030}},
+		{ConfigYearFromGitFirstCommit, "main.go", CopyrightData{FirstYear: 2015, Year: 2015}},
+		{ConfigYearFromGitLastCommit, "main.go", CopyrightData{FirstYear: 2015, Year: 2018}},
+		{ConfigYearFromGitFirstCommit, "pkg/file.go", CopyrightData{FirstYear: 2020, Year: 2020}},
+		{ConfigYearFromGitLastCommit, "pkg/file.go", CopyrightData{FirstYear: 2020, Year: 2020}},
+		// not committed yet
+		{ConfigYearFromGitFirstCommit, "new.go", CopyrightData{FirstYear: 2030, Year: 2030}},
+		{ConfigYearFromGitLastCommit, "new.go", CopyrightData{FirstYear: 2030, Year: 2030}},
+		{ConfigYearFromModTime, "new.go", CopyrightData{FirstYear: 2012, Year: 2012}},
+		{ConfigYearFromModTime, "not-found.go", CopyrightData{FirstYear: 2030, Year: 2030}},
+	}
+	for _, testItem := range testData {
+		resolver := newYearResolver(testItem.source)
+		resolver.now = func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local) }
+		assert.Equal(t, testItem.data, resolver.years(filepath.Join(dir, testItem.file)), "%d: %s", testItem.source, testItem.file)
+	}
+}
+
+func TestYearResolverOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	fileName := filepath.Join(t.TempDir(), "file.go")
+	require.NoError(t, os.WriteFile(fileName, []byte(testSource), 0600))
+	resolver := newYearResolver(ConfigYearFromGitLastCommit)
+	currentYear := time.Now().Year()
+	assert.Equal(t, CopyrightData{FirstYear: currentYear, Year: currentYear}, resolver.years(fileName))
+}
+
+func TestNoticeWithGitYears(t *testing.T) {
+	dir := initGitRepository(t)
+	template := "/*\n * Copyright (C) {{.YearRange}} TestCorp.\n */\n"
+	commitFile(t, dir, "copyright.txt", template, 2010)
+	commitFile(t, dir, "created.go", testSource, 2016)
+	commitFile(t, dir, "created.go", testSource+"\n", 2019)
+	commitFile(t, dir, "outdated.go", "/*\n * Copyright (C) 2015 TestCorp.\n */\n"+testSource, 2015)
+	commitFile(t, dir, "outdated.go", "/*\n * Copyright (C) 2015 TestCorp.\n */\n"+testSource+"\n", 2017)
+
+	testData := []struct {
+		source   ConfigYearSource
+		file     string
+		expected string
+	}{
+		{ConfigYearFromGitFirstCommit, "created.go", "/*\n * Copyright (C) 2016 TestCorp.\n */\n" + testSource + "\n"},
+		{ConfigYearFromGitLastCommit, "created.go", "/*\n * Copyright (C) 2016-2019 TestCorp.\n */\n" + testSource + "\n"},
+		// the year is not changed on the first year
+		{ConfigYearFromGitFirstCommit, "outdated.go", "/*\n * Copyright (C) 2015 TestCorp.\n */\n" + testSource + "\n"},
+		{ConfigYearFromGitLastCommit, "outdated.go", "/*\n * Copyright (C) 2015-2017 TestCorp.\n */\n" + testSource + "\n"},
+	}
+	for _, testItem := range testData {
+		profile := ConfigProfile{
+			Copyright:  filepath.Join(dir, "copyright.txt"),
+			Year:       yearPolicy(ConfigUpdateYear),
+			YearSource: &testItem.source,
+		}
+		notice, err := loadNotice("test", profile)
+		require.NoError(t, err)
+
+		fileName := filepath.Join(dir, testItem.file)
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		notice.checkForCopyrightNoticeInFile(NewFile(bufferSize), FileEntry{Name: fileName, Size: info.Size()}, NewResults())
+
+		result, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		assert.Equal(t, testItem.expected, string(result), "%d: %s", testItem.source, testItem.file)
+
+		_, err = git(dir, "checkout", "--quiet", "--", testItem.file)
+		require.NoError(t, err)
+	}
+}